@@ -0,0 +1,360 @@
+// Package a2a reads and writes bidprentjes in the A2A (Archives to All) XML
+// format that Dutch archives use to exchange person records.
+package a2a
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+)
+
+const (
+	// Namespace is the XML namespace of A2A documents
+	Namespace = "http://Mindbus.nl/A2A"
+	// Schema is the location of the A2A XML schema
+	Schema = "https://www.openarch.nl/schemas/A2AAllInOne_v.1.7.xsd"
+	// Version is the A2A schema version that is written
+	Version = "1.7"
+
+	// SourceType marks our records in A2A
+	SourceType = "Bidprentje"
+
+	eventDeath       = "Overlijden"
+	relationDeceased = "Overledene"
+	remarkPhoto      = "Foto"
+	collection       = "Bidprentjes"
+)
+
+// Document is a single A2A record
+type Document struct {
+	XMLName    xml.Name     `xml:"A2A"`
+	Xmlns      string       `xml:"xmlns,attr,omitempty"`
+	Version    string       `xml:"Version,attr,omitempty"`
+	Persons    []Person     `xml:"Person"`
+	Events     []Event      `xml:"Event"`
+	RelationEP []RelationEP `xml:"RelationEP"`
+	Source     Source       `xml:"Source"`
+}
+
+type Person struct {
+	PID        string     `xml:"pid,attr"`
+	Name       PersonName `xml:"PersonName"`
+	BirthDate  *Date      `xml:"BirthDate"`
+	BirthPlace *Place     `xml:"BirthPlace"`
+}
+
+type PersonName struct {
+	FirstName      string `xml:"PersonNameFirstName,omitempty"`
+	PrefixLastName string `xml:"PersonNamePrefixLastName,omitempty"`
+	LastName       string `xml:"PersonNameLastName,omitempty"`
+}
+
+type Event struct {
+	EID   string `xml:"eid,attr"`
+	Type  string `xml:"EventType"`
+	Date  *Date  `xml:"EventDate"`
+	Place *Place `xml:"EventPlace"`
+}
+
+type RelationEP struct {
+	PersonKeyRef string `xml:"PersonKeyRef"`
+	EventKeyRef  string `xml:"EventKeyRef"`
+	RelationType string `xml:"RelationType"`
+}
+
+type Source struct {
+	Type        string          `xml:"SourceType"`
+	Reference   SourceReference `xml:"SourceReference"`
+	Scans       []Scan          `xml:"SourceAvailableScans>Scan"`
+	DigitalOrig string          `xml:"SourceDigitalOriginal,omitempty"`
+	RecordGUID  string          `xml:"RecordGUID,omitempty"`
+	Remarks     []Remark        `xml:"SourceRemark"`
+}
+
+type SourceReference struct {
+	Collection     string `xml:"Collection,omitempty"`
+	DocumentNumber string `xml:"DocumentNumber,omitempty"`
+}
+
+type Scan struct {
+	OrderSequenceNumber int    `xml:"OrderSequenceNumber"`
+	URI                 string `xml:"Uri"`
+}
+
+type Remark struct {
+	Key   string `xml:"Key,attr"`
+	Value string `xml:"Value"`
+}
+
+// Date is an A2A date, of which any part may be missing
+type Date struct {
+	Day   int `xml:"Day,omitempty"`
+	Month int `xml:"Month,omitempty"`
+	Year  int `xml:"Year,omitempty"`
+}
+
+type Place struct {
+	Place string `xml:"Place"`
+}
+
+// Options holds the links written into exported documents
+type Options struct {
+	Permalink func(id string) string
+	ScanURLs  func(*models.Bidprentje) []string
+}
+
+// FromBidprentje returns the A2A document of b: the deceased as a person,
+// the death as an event and the bidprentje itself as the source
+func FromBidprentje(b *models.Bidprentje, opts Options) *Document {
+	doc := &Document{
+		Xmlns:   Namespace,
+		Version: Version,
+		Persons: []Person{{
+			PID: "Person1",
+			Name: PersonName{
+				FirstName:      b.Voornaam,
+				PrefixLastName: b.Tussenvoegsel,
+				LastName:       b.Achternaam,
+			},
+			BirthDate:  newDate(b.Geboortedatum),
+			BirthPlace: newPlace(b.Geboorteplaats),
+		}},
+		Events: []Event{{
+			EID:   "Event1",
+			Type:  eventDeath,
+			Date:  newDate(b.Overlijdensdatum),
+			Place: newPlace(b.Overlijdensplaats),
+		}},
+		RelationEP: []RelationEP{{
+			PersonKeyRef: "Person1",
+			EventKeyRef:  "Event1",
+			RelationType: relationDeceased,
+		}},
+		Source: Source{
+			Type: SourceType,
+			Reference: SourceReference{
+				Collection:     collection,
+				DocumentNumber: b.ID,
+			},
+			RecordGUID: RecordGUID(b.ID),
+			Remarks:    []Remark{{Key: remarkPhoto, Value: yesNo(b.Photo)}},
+		},
+	}
+
+	if opts.Permalink != nil {
+		doc.Source.DigitalOrig = opts.Permalink(b.ID)
+	}
+	if opts.ScanURLs != nil {
+		for i, uri := range opts.ScanURLs(b) {
+			doc.Source.Scans = append(doc.Source.Scans, Scan{OrderSequenceNumber: i + 1, URI: uri})
+		}
+	}
+	return doc
+}
+
+// Bidprentje maps the document onto a bidprentje. The deceased is the
+// person related to the death event, or else the first person. Problems
+// that do not prevent the import, like incomplete dates, are returned as
+// messages; a document without a document number or GUID is an error.
+func (doc *Document) Bidprentje() (*models.Bidprentje, []string, error) {
+	id := strings.TrimSpace(doc.Source.Reference.DocumentNumber)
+	if id == "" {
+		id = strings.Trim(strings.TrimSpace(doc.Source.RecordGUID), "{}")
+	}
+	if id == "" {
+		return nil, nil, fmt.Errorf("no DocumentNumber or RecordGUID")
+	}
+
+	death := doc.deathEvent()
+	person := doc.deceased(death)
+
+	b := &models.Bidprentje{ID: id}
+	var problems []string
+
+	if person != nil {
+		b.Voornaam = strings.TrimSpace(person.Name.FirstName)
+		b.Tussenvoegsel = strings.TrimSpace(person.Name.PrefixLastName)
+		b.Achternaam = strings.TrimSpace(person.Name.LastName)
+		if person.BirthPlace != nil {
+			b.Geboorteplaats = strings.TrimSpace(person.BirthPlace.Place)
+		}
+		if date, err := person.BirthDate.Time(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: birth date %v", id, err))
+		} else {
+			b.Geboortedatum = date
+		}
+	}
+
+	if death != nil {
+		if death.Place != nil {
+			b.Overlijdensplaats = strings.TrimSpace(death.Place.Place)
+		}
+		if date, err := death.Date.Time(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: death date %v", id, err))
+		} else {
+			b.Overlijdensdatum = date
+		}
+	}
+
+	for _, remark := range doc.Source.Remarks {
+		if strings.EqualFold(remark.Key, remarkPhoto) {
+			b.Photo = isYes(remark.Value)
+		}
+	}
+	for _, scan := range doc.Source.Scans {
+		if name := ScanName(scan.URI); name != "" {
+			b.Scans = append(b.Scans, name)
+		}
+	}
+
+	return b, problems, nil
+}
+
+// deathEvent returns the death event of the document, if any
+func (doc *Document) deathEvent() *Event {
+	for i := range doc.Events {
+		if strings.EqualFold(doc.Events[i].Type, eventDeath) {
+			return &doc.Events[i]
+		}
+	}
+	return nil
+}
+
+// deceased returns the person that died in the death event, or else the
+// first person of the document
+func (doc *Document) deceased(death *Event) *Person {
+	if death != nil {
+		for _, relation := range doc.RelationEP {
+			if relation.EventKeyRef != death.EID || !strings.EqualFold(relation.RelationType, relationDeceased) {
+				continue
+			}
+			for i := range doc.Persons {
+				if doc.Persons[i].PID == relation.PersonKeyRef {
+					return &doc.Persons[i]
+				}
+			}
+		}
+	}
+	if len(doc.Persons) > 0 {
+		return &doc.Persons[0]
+	}
+	return nil
+}
+
+// Decode reads every A2A document in r and calls fn for each. The
+// documents may be the root element or be wrapped in any other elements,
+// like an OAI-PMH response or a collection of records.
+func Decode(r io.Reader, fn func(*Document) error) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read A2A XML: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "A2A" {
+			continue
+		}
+		var doc Document
+		if err := decoder.DecodeElement(&doc, &start); err != nil {
+			return fmt.Errorf("failed to read A2A document: %v", err)
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+}
+
+// Encode writes doc as indented XML, without an XML declaration
+func Encode(w io.Writer, doc *Document) error {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write A2A document: %v", err)
+	}
+	return nil
+}
+
+// RecordGUID returns a stable GUID for the bidprentje with the given ID,
+// so harvesters recognise a record across exports
+func RecordGUID(id string) string {
+	sum := sha1.Sum([]byte("bidprentje:" + id))
+	// Name-based UUID, version 5
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("{%x-%x-%x-%x-%x}", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// ScanName returns the scan ID of a scan link, the file name without its
+// extension, e.g. "scan1a" for "https://cdn.example.org/scan1a.jpg"
+func ScanName(uri string) string {
+	p := strings.TrimSpace(uri)
+	if u, err := url.Parse(p); err == nil && u.Path != "" {
+		p = u.Path
+	}
+	name := path.Base(p)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// Time returns the date as a time, the zero time when the date is missing
+// and an error when it is incomplete or invalid
+func (d *Date) Time() (time.Time, error) {
+	if d == nil || (d.Day == 0 && d.Month == 0 && d.Year == 0) {
+		return time.Time{}, nil
+	}
+	if d.Day == 0 || d.Month == 0 || d.Year == 0 {
+		return time.Time{}, fmt.Errorf("%s is incomplete", d)
+	}
+	t := time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != d.Day || int(t.Month()) != d.Month {
+		return time.Time{}, fmt.Errorf("%s is not a valid date", d)
+	}
+	return t, nil
+}
+
+func (d *Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func newDate(t time.Time) *Date {
+	if t.IsZero() {
+		return nil
+	}
+	return &Date{Day: t.Day(), Month: int(t.Month()), Year: t.Year()}
+}
+
+func newPlace(place string) *Place {
+	if strings.TrimSpace(place) == "" {
+		return nil
+	}
+	return &Place{Place: place}
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "ja"
+	}
+	return "nee"
+}
+
+func isYes(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "ja", "yes", "true", "1":
+		return true
+	}
+	return false
+}
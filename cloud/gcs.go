@@ -0,0 +1,139 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStorage stores files in a Google Cloud Storage bucket
+type GCSStorage struct {
+	client     *storage.Client
+	bucketName string
+}
+
+func NewGCSStorage(ctx context.Context, bucketName string) (*GCSStorage, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %v", err)
+	}
+
+	return &GCSStorage{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+func (s *GCSStorage) DownloadFile(ctx context.Context, filename string) (io.Reader, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	bucket := s.client.Bucket(s.bucketName)
+	obj := bucket.Object(filename)
+
+	reader, err := obj.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reader: %v", err)
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+
+	reader.Close()
+
+	return bytes.NewReader(content), nil
+}
+
+func (s *GCSStorage) MoveFile(ctx context.Context, srcPath, dstPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	bucket := s.client.Bucket(s.bucketName)
+	src := bucket.Object(srcPath)
+	dst := bucket.Object(dstPath)
+
+	// Copy the object to the new location
+	if _, err := dst.CopierFrom(src).Run(ctx); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to move %s: %w", srcPath, ErrNotFound)
+		}
+		return fmt.Errorf("failed to copy file: %v", err)
+	}
+
+	// Delete the original object
+	if err := src.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete original file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *GCSStorage) UploadFile(ctx context.Context, filename string, reader io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	bucket := s.client.Bucket(s.bucketName)
+	obj := bucket.Object(filename)
+
+	writer := obj.NewWriter(ctx)
+	if _, err := io.Copy(writer, reader); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to copy data to GCS: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %v", err)
+	}
+
+	return nil
+}
+
+func (s *GCSStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	var names []string
+	it := s.client.Bucket(s.bucketName).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		names = append(names, attrs.Name)
+	}
+
+	return names, nil
+}
+
+func (s *GCSStorage) Delete(ctx context.Context, filename string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	if err := s.client.Bucket(s.bucketName).Object(filename).Delete(ctx); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", filename, ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *GCSStorage) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,157 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStorage stores files in a directory on the local filesystem
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &LocalStorage{root: root}, nil
+}
+
+// path returns the location of filename inside the root directory
+func (s *LocalStorage) path(filename string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(filename))
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file name %q", filename)
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+func (s *LocalStorage) DownloadFile(ctx context.Context, filename string) (io.Reader, error) {
+	path, err := s.path(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+
+	return bytes.NewReader(content), nil
+}
+
+func (s *LocalStorage) MoveFile(ctx context.Context, srcPath, dstPath string) error {
+	src, err := s.path(srcPath)
+	if err != nil {
+		return err
+	}
+	dst, err := s.path(dstPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("failed to move %s: %w", srcPath, ErrNotFound)
+		}
+		return fmt.Errorf("failed to move file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) UploadFile(ctx context.Context, filename string, reader io.Reader) error {
+	path, err := s.path(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := io.Copy(tempFile, reader); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to copy data to file: %v", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to store file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %v", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, filename string) error {
+	path, err := s.path(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", filename, ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) Close() error {
+	return nil
+}
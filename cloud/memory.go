@@ -0,0 +1,93 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStorage keeps files in memory. It is meant for tests and for
+// running without any persistent backend.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		files: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStorage) DownloadFile(ctx context.Context, filename string) (io.Reader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, ok := s.files[filename]
+	if !ok {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, ErrNotFound)
+	}
+
+	return bytes.NewReader(content), nil
+}
+
+func (s *MemoryStorage) MoveFile(ctx context.Context, srcPath, dstPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.files[srcPath]
+	if !ok {
+		return fmt.Errorf("failed to move %s: %w", srcPath, ErrNotFound)
+	}
+
+	s.files[dstPath] = content
+	delete(s.files, srcPath)
+	return nil
+}
+
+func (s *MemoryStorage) UploadFile(ctx context.Context, filename string, reader io.Reader) error {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[filename] = content
+	return nil
+}
+
+func (s *MemoryStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name := range s.files {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.files[filename]; !ok {
+		return fmt.Errorf("failed to delete %s: %w", filename, ErrNotFound)
+	}
+
+	delete(s.files, filename)
+	return nil
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,212 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const defaultS3Endpoint = "https://s3.amazonaws.com"
+
+// S3Options configures an S3-compatible storage backend
+type S3Options struct {
+	// Endpoint is the base URL of the S3 service, e.g. http://minio:9000
+	Endpoint string
+	// Region of the bucket, looked up from the service when empty
+	Region string
+	// PathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint
+	PathStyle bool
+	// AccessKey and SecretKey are read from the AWS_* or MINIO_* environment
+	// variables when empty
+	AccessKey string
+	SecretKey string
+}
+
+// S3Storage stores files in an S3-compatible bucket such as AWS S3 or MinIO
+type S3Storage struct {
+	client     *minio.Client
+	bucketName string
+}
+
+func NewS3Storage(bucketName string, opts S3Options) (*S3Storage, error) {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = defaultS3Endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+	})
+	if opts.AccessKey != "" {
+		creds = credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, "")
+	}
+
+	lookup := minio.BucketLookupAuto
+	if opts.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        creds,
+		Secure:       u.Scheme == "https",
+		Region:       opts.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %v", err)
+	}
+
+	return &S3Storage{
+		client:     client,
+		bucketName: bucketName,
+	}, nil
+}
+
+// s3OptionsFromURL reads the endpoint, region and path_style query
+// parameters of an s3:// storage URL
+func s3OptionsFromURL(u *url.URL) (S3Options, error) {
+	q := u.Query()
+	opts := S3Options{
+		Endpoint: q.Get("endpoint"),
+		Region:   q.Get("region"),
+	}
+	if v := q.Get("path_style"); v != "" {
+		pathStyle, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid path_style %q", v)
+		}
+		opts.PathStyle = pathStyle
+	}
+	return opts, nil
+}
+
+// isNotFound reports whether err is an S3 missing object error
+func isNotFound(err error) bool {
+	code := minio.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+func (s *S3Storage) DownloadFile(ctx context.Context, filename string) (io.Reader, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	obj, err := s.client.GetObject(ctx, s.bucketName, filename, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reader: %v", err)
+	}
+	defer obj.Close()
+
+	content, err := io.ReadAll(obj)
+	if isNotFound(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+
+	return bytes.NewReader(content), nil
+}
+
+func (s *S3Storage) MoveFile(ctx context.Context, srcPath, dstPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	// Copy the object to the new location
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucketName, Object: dstPath},
+		minio.CopySrcOptions{Bucket: s.bucketName, Object: srcPath})
+	if isNotFound(err) {
+		return fmt.Errorf("failed to move %s: %w", srcPath, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %v", err)
+	}
+
+	// Delete the original object
+	if err := s.client.RemoveObject(ctx, s.bucketName, srcPath, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete original file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) UploadFile(ctx context.Context, filename string, reader io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	// S3 needs the object size up front, buffer readers of unknown length
+	size := int64(-1)
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		size = int64(r.Len())
+	case *os.File:
+		if fi, err := r.Stat(); err == nil {
+			size = fi.Size()
+		}
+	}
+	if size < 0 {
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read upload: %v", err)
+		}
+		reader = bytes.NewReader(content)
+		size = int64(len(content))
+	}
+
+	if _, err := s.client.PutObject(ctx, s.bucketName, filename, reader, size, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to copy data to S3: %v", err)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	var names []string
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", obj.Err)
+		}
+		names = append(names, obj.Key)
+	}
+
+	return names, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, filename string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	// S3 deletes are idempotent, check first to report missing files
+	if _, err := s.client.StatObject(ctx, s.bucketName, filename, minio.StatObjectOptions{}); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("failed to delete %s: %w", filename, ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	if err := s.client.RemoveObject(ctx, s.bucketName, filename, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) Close() error {
+	return nil
+}
@@ -1,96 +1,73 @@
 package cloud
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"time"
-
-	"cloud.google.com/go/storage"
+	"net/url"
 )
 
-type StorageClient struct {
-	client     *storage.Client
-	bucketName string
+// ErrNotFound is returned when a file does not exist in the storage backend
+var ErrNotFound = errors.New("file not found")
+
+// Storage is a backend that holds index backups and CSV source files
+type Storage interface {
+	// DownloadFile returns the contents of a file
+	DownloadFile(ctx context.Context, filename string) (io.Reader, error)
+	// UploadFile creates or replaces a file
+	UploadFile(ctx context.Context, filename string, reader io.Reader) error
+	// MoveFile renames a file
+	MoveFile(ctx context.Context, srcPath, dstPath string) error
+	// List returns the names of all files starting with prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes a file
+	Delete(ctx context.Context, filename string) error
+	// Close releases the resources held by the backend
+	Close() error
 }
 
-func NewStorageClient(ctx context.Context, bucketName string) (*StorageClient, error) {
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create storage client: %v", err)
-	}
-
-	return &StorageClient{
-		client:     client,
-		bucketName: bucketName,
-	}, nil
-}
-
-func (s *StorageClient) DownloadFile(ctx context.Context, filename string) (io.Reader, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
-	defer cancel()
-
-	bucket := s.client.Bucket(s.bucketName)
-	obj := bucket.Object(filename)
-
-	reader, err := obj.NewReader(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create reader: %v", err)
+// NewStorage creates a storage backend from a URL:
+//   - gs://bucket for Google Cloud Storage
+//   - s3://bucket?endpoint=http://minio:9000&region=us-east-1&path_style=true
+//     for S3-compatible storage, credentials come from the environment
+//   - file:///var/backups for a local directory
+//   - mem:// for an in-memory backend, mainly useful in tests
+//
+// An empty URL returns a nil Storage, meaning local-only mode.
+func NewStorage(ctx context.Context, storageURL string) (Storage, error) {
+	if storageURL == "" {
+		return nil, nil
 	}
 
-	content, err := io.ReadAll(reader)
+	u, err := url.Parse(storageURL)
 	if err != nil {
-		reader.Close()
-		return nil, fmt.Errorf("failed to read file content: %v", err)
+		return nil, fmt.Errorf("invalid storage URL %q: %v", storageURL, err)
 	}
 
-	reader.Close()
-
-	return bytes.NewReader(content), nil
-}
-
-func (s *StorageClient) MoveFile(ctx context.Context, srcPath, dstPath string) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
-	defer cancel()
-
-	bucket := s.client.Bucket(s.bucketName)
-	src := bucket.Object(srcPath)
-	dst := bucket.Object(dstPath)
-
-	// Copy the object to the new location
-	if _, err := dst.CopierFrom(src).Run(ctx); err != nil {
-		return fmt.Errorf("failed to copy file: %v", err)
+	switch u.Scheme {
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid storage URL %q: missing bucket name", storageURL)
+		}
+		return NewGCSStorage(ctx, u.Host)
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid storage URL %q: missing bucket name", storageURL)
+		}
+		opts, err := s3OptionsFromURL(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage URL %q: %v", storageURL, err)
+		}
+		return NewS3Storage(u.Host, opts)
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid storage URL %q: missing directory", storageURL)
+		}
+		return NewLocalStorage(u.Path)
+	case "mem":
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage URL scheme %q", u.Scheme)
 	}
-
-	// Delete the original object
-	if err := src.Delete(ctx); err != nil {
-		return fmt.Errorf("failed to delete original file: %v", err)
-	}
-
-	return nil
-}
-
-func (s *StorageClient) UploadFile(ctx context.Context, filename string, reader io.Reader) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
-	defer cancel()
-
-	bucket := s.client.Bucket(s.bucketName)
-	obj := bucket.Object(filename)
-
-	writer := obj.NewWriter(ctx)
-	if _, err := io.Copy(writer, reader); err != nil {
-		writer.Close()
-		return fmt.Errorf("failed to copy data to GCS: %v", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to close writer: %v", err)
-	}
-
-	return nil
-}
-
-func (s *StorageClient) Close() error {
-	return s.client.Close()
 }
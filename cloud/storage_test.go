@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStorageBackends(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := map[string]Storage{
+		"local":  local,
+		"memory": NewMemoryStorage(),
+	}
+
+	for name, storage := range backends {
+		t.Run(name, func(t *testing.T) {
+			testStorage(t, storage)
+		})
+	}
+}
+
+// testStorage runs the operations every backend has to support
+func testStorage(t *testing.T, storage Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := storage.UploadFile(ctx, "data/bidprentjes.csv", strings.NewReader("1,Jan")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.UploadFile(ctx, "index/bidprentjes.bleve.tar.gz", strings.NewReader("index")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := storage.DownloadFile(ctx, "data/bidprentjes.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	if string(content) != "1,Jan" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	if err := storage.MoveFile(ctx, "data/bidprentjes.csv", "data/processed.csv"); err != nil {
+		t.Fatal(err)
+	}
+	names, err := storage.List(ctx, "data/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"data/processed.csv"}) {
+		t.Errorf("Unexpected files after move: %v", names)
+	}
+
+	if err := storage.Delete(ctx, "data/processed.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.DownloadFile(ctx, "data/processed.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := storage.Delete(ctx, "data/processed.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a missing file, got %v", err)
+	}
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.UploadFile(context.Background(), "../escape.txt", strings.NewReader("x")); err == nil {
+		t.Error("Expected an error for a path outside the storage directory")
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	ctx := context.Background()
+
+	if s, err := NewStorage(ctx, ""); err != nil || s != nil {
+		t.Errorf("Expected nil storage for empty URL, got %v, %v", s, err)
+	}
+	if s, err := NewStorage(ctx, "mem://"); err != nil {
+		t.Error(err)
+	} else if _, ok := s.(*MemoryStorage); !ok {
+		t.Errorf("Expected memory storage, got %T", s)
+	}
+	if s, err := NewStorage(ctx, "file://"+t.TempDir()); err != nil {
+		t.Error(err)
+	} else if _, ok := s.(*LocalStorage); !ok {
+		t.Errorf("Expected local storage, got %T", s)
+	}
+	if _, err := NewStorage(ctx, "ftp://example.com"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
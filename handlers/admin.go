@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/store"
+	"bidprentjes-api/translations"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidDate = errors.New("dates should be in YYYY-MM-DD format")
+
+// bidprentjeForm holds the raw values of the admin edit form
+type bidprentjeForm struct {
+	ID                string `form:"id"`
+	Voornaam          string `form:"voornaam"`
+	Tussenvoegsel     string `form:"tussenvoegsel"`
+	Achternaam        string `form:"achternaam"`
+	Geboortedatum     string `form:"geboortedatum"`
+	Geboorteplaats    string `form:"geboorteplaats"`
+	Overlijdensdatum  string `form:"overlijdensdatum"`
+	Overlijdensplaats string `form:"overlijdensplaats"`
+	Photo             bool   `form:"photo"`
+	Scans             string `form:"scans"`
+}
+
+func formFromBidprentje(b *models.Bidprentje) bidprentjeForm {
+	f := bidprentjeForm{
+		ID:                b.ID,
+		Voornaam:          b.Voornaam,
+		Tussenvoegsel:     b.Tussenvoegsel,
+		Achternaam:        b.Achternaam,
+		Geboorteplaats:    b.Geboorteplaats,
+		Overlijdensplaats: b.Overlijdensplaats,
+		Photo:             b.Photo,
+		Scans:             strings.Join(b.Scans, "\n"),
+	}
+	if !b.Geboortedatum.IsZero() {
+		f.Geboortedatum = b.Geboortedatum.Format("2006-01-02")
+	}
+	if !b.Overlijdensdatum.IsZero() {
+		f.Overlijdensdatum = b.Overlijdensdatum.Format("2006-01-02")
+	}
+	return f
+}
+
+// toBidprentje converts and validates the submitted form values
+func (f bidprentjeForm) toBidprentje() (*models.Bidprentje, error) {
+	geboortedatum, err := parseFormDate(f.Geboortedatum)
+	if err != nil {
+		return nil, err
+	}
+	overlijdensdatum, err := parseFormDate(f.Overlijdensdatum)
+	if err != nil {
+		return nil, err
+	}
+
+	var scans []string
+	for _, scan := range strings.FieldsFunc(f.Scans, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	}) {
+		if scan = strings.TrimSpace(scan); scan != "" {
+			scans = append(scans, scan)
+		}
+	}
+
+	b := &models.Bidprentje{
+		ID:                strings.TrimSpace(f.ID),
+		Voornaam:          strings.TrimSpace(f.Voornaam),
+		Tussenvoegsel:     strings.TrimSpace(f.Tussenvoegsel),
+		Achternaam:        strings.TrimSpace(f.Achternaam),
+		Geboortedatum:     geboortedatum,
+		Geboorteplaats:    strings.TrimSpace(f.Geboorteplaats),
+		Overlijdensdatum:  overlijdensdatum,
+		Overlijdensplaats: strings.TrimSpace(f.Overlijdensplaats),
+		Photo:             f.Photo,
+		Scans:             scans,
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// parseFormDate parses an optional YYYY-MM-DD date
+func parseFormDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errInvalidDate
+	}
+	return parsed, nil
+}
+
+// validationMessage returns the localized message for a validation error
+func validationMessage(t translations.Translations, err error) string {
+	switch {
+	case errors.Is(err, models.ErrIDRequired):
+		return t.IDRequired
+	case errors.Is(err, models.ErrDeathBeforeBirth):
+		return t.DeathBeforeBirth
+	case errors.Is(err, errInvalidDate):
+		return t.InvalidDate
+	}
+	return t.SaveError
+}
+
+// adminLang returns the language of an admin page from the query or form
+func adminLang(c *gin.Context) string {
+	if lang := c.Query("lang"); lang != "" {
+		return lang
+	}
+	return c.DefaultPostForm("lang", "nl")
+}
+
+// adminRedirect sends the browser back to the admin search page with a status
+func adminRedirect(c *gin.Context, lang, status string) {
+	c.Redirect(http.StatusSeeOther, "/admin/search?"+url.Values{
+		"lang":   {lang},
+		"status": {status},
+	}.Encode())
+}
+
+func (h *Handler) renderEditForm(c *gin.Context, status int, lang string, form bidprentjeForm, isNew bool, message string) {
+	t := translations.GetTranslation(lang)
+	title := t.Edit
+	action := "/admin/bidprentjes/" + url.PathEscape(form.ID)
+	if isNew {
+		title = t.CreateNew
+		action = "/admin/bidprentjes"
+	}
+
+	c.HTML(status, "edit.html", gin.H{
+		"form":      form,
+		"isNew":     isNew,
+		"action":    action,
+		"lang":      lang,
+		"languages": translations.SupportedLanguages,
+		"t":         t,
+		"title":     title,
+		"error":     message,
+	})
+}
+
+// AdminSearch renders the search page with edit and delete actions
+func (h *Handler) AdminSearch(c *gin.Context) {
+	h.renderSearch(c, "/admin/search")
+}
+
+// AdminNewForm renders an empty form for a new bidprentje
+func (h *Handler) AdminNewForm(c *gin.Context) {
+	h.renderEditForm(c, http.StatusOK, adminLang(c), bidprentjeForm{}, true, "")
+}
+
+// AdminEditForm renders the form for an existing bidprentje
+func (h *Handler) AdminEditForm(c *gin.Context) {
+	lang := adminLang(c)
+	b, exists := h.store.Get(c.Param("id"))
+	if !exists {
+		t := translations.GetTranslation(lang)
+		h.renderEditForm(c, http.StatusNotFound, lang, bidprentjeForm{ID: c.Param("id")}, false, t.NotFound)
+		return
+	}
+	h.renderEditForm(c, http.StatusOK, lang, formFromBidprentje(b), false, "")
+}
+
+// AdminCreate handles the submitted form for a new bidprentje
+func (h *Handler) AdminCreate(c *gin.Context) {
+	lang := adminLang(c)
+	t := translations.GetTranslation(lang)
+
+	var form bidprentjeForm
+	if err := c.ShouldBind(&form); err != nil {
+		h.renderEditForm(c, http.StatusBadRequest, lang, form, true, t.SaveError)
+		return
+	}
+
+	b, err := form.toBidprentje()
+	if err != nil {
+		h.renderEditForm(c, http.StatusBadRequest, lang, form, true, validationMessage(t, err))
+		return
+	}
+
+	if err := h.store.CreateIfAbsent(b); errors.Is(err, store.ErrExists) {
+		h.renderEditForm(c, http.StatusConflict, lang, form, true, t.AlreadyExists)
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to create bidprentje", "id", b.ID, "error", err)
+		h.renderEditForm(c, http.StatusInternalServerError, lang, form, true, t.SaveError)
+		return
+	}
+
+	adminRedirect(c, lang, "saved")
+}
+
+// AdminUpdate handles the submitted form for an existing bidprentje
+func (h *Handler) AdminUpdate(c *gin.Context) {
+	lang := adminLang(c)
+	t := translations.GetTranslation(lang)
+	id := c.Param("id")
+
+	var form bidprentjeForm
+	if err := c.ShouldBind(&form); err != nil {
+		h.renderEditForm(c, http.StatusBadRequest, lang, form, false, t.SaveError)
+		return
+	}
+	form.ID = id
+
+	b, err := form.toBidprentje()
+	if err != nil {
+		h.renderEditForm(c, http.StatusBadRequest, lang, form, false, validationMessage(t, err))
+		return
+	}
+
+	if err := h.store.UpdateIfExists(b); errors.Is(err, store.ErrNotFound) {
+		h.renderEditForm(c, http.StatusNotFound, lang, form, false, t.NotFound)
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to update bidprentje", "id", b.ID, "error", err)
+		h.renderEditForm(c, http.StatusInternalServerError, lang, form, false, t.SaveError)
+		return
+	}
+
+	adminRedirect(c, lang, "saved")
+}
+
+// AdminDelete handles the delete button of the admin pages
+func (h *Handler) AdminDelete(c *gin.Context) {
+	lang := adminLang(c)
+	id := c.Param("id")
+
+	if err := h.store.DeleteIfExists(id); errors.Is(err, store.ErrNotFound) {
+		adminRedirect(c, lang, "delete_error")
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to delete bidprentje", "id", id, "error", err)
+		adminRedirect(c, lang, "delete_error")
+		return
+	}
+
+	adminRedirect(c, lang, "deleted")
+}
+
+// APICreate stores a new bidprentje sent as JSON
+func (h *Handler) APICreate(c *gin.Context) {
+	var b models.Bidprentje
+	if err := c.ShouldBindJSON(&b); err != nil {
+		apiError(c, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	b.ID = strings.TrimSpace(b.ID)
+	if err := b.Validate(); err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.CreateIfAbsent(&b); errors.Is(err, store.ErrExists) {
+		apiError(c, http.StatusConflict, fmt.Sprintf("bidprentje %q already exists", b.ID))
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to create bidprentje", "id", b.ID, "error", err)
+		apiError(c, http.StatusInternalServerError, "failed to create bidprentje")
+		return
+	}
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// APIUpdate replaces an existing bidprentje with the JSON body
+func (h *Handler) APIUpdate(c *gin.Context) {
+	id := c.Param("id")
+
+	var b models.Bidprentje
+	if err := c.ShouldBindJSON(&b); err != nil {
+		apiError(c, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if b.ID != "" && b.ID != id {
+		apiError(c, http.StatusBadRequest, "id in body does not match id in path")
+		return
+	}
+	b.ID = id
+	if err := b.Validate(); err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.UpdateIfExists(&b); errors.Is(err, store.ErrNotFound) {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("bidprentje %q not found", id))
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to update bidprentje", "id", id, "error", err)
+		apiError(c, http.StatusInternalServerError, "failed to update bidprentje")
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+// APIDelete removes a bidprentje
+func (h *Handler) APIDelete(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.store.DeleteIfExists(id); errors.Is(err, store.ErrNotFound) {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("bidprentje %q not found", id))
+		return
+	} else if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to delete bidprentje", "id", id, "error", err)
+		apiError(c, http.StatusInternalServerError, "failed to delete bidprentje")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReloadSynonyms reads the first-name synonyms file again, so changes take
+// effect without a restart
+func (h *Handler) ReloadSynonyms(c *gin.Context) {
+	synonyms, err := h.store.ReloadSynonyms()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to reload synonyms", "error", err)
+		apiError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": synonyms.Groups()})
+}
+
+// Reindex starts rebuilding the search index with the current mapping and
+// analyzers in the background. Searches keep using the current index until
+// the new one is complete.
+func (h *Handler) Reindex(c *gin.Context) {
+	job, err := h.store.StartReindex(context.WithoutCancel(c.Request.Context()))
+	if errors.Is(err, store.ErrReindexRunning) {
+		apiError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "failed to start reindex")
+		return
+	}
+
+	c.Header("Location", "/admin/reindex")
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ReindexStatus returns the state of the last reindex
+func (h *Handler) ReindexStatus(c *gin.Context) {
+	job, ok := h.store.ReindexStatus()
+	if !ok {
+		apiError(c, http.StatusNotFound, "no reindex has been started")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
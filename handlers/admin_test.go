@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIWrites(t *testing.T) {
+	h, s := newTestHandler(t)
+	if err := s.Create(&models.Bidprentje{ID: "1", Achternaam: "Jansen"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/api/v1/bidprentjes", h.APICreate)
+	r.PUT("/api/v1/bidprentjes/:id", h.APIUpdate)
+	r.DELETE("/api/v1/bidprentjes/:id", h.APIDelete)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		error  string
+	}{
+		{"create", http.MethodPost, "/api/v1/bidprentjes", `{"id": " 2 ", "achternaam": "Peeters"}`, http.StatusCreated, ""},
+		{"create existing", http.MethodPost, "/api/v1/bidprentjes", `{"id": "1", "achternaam": "Janssen"}`, http.StatusConflict, `bidprentje "1" already exists`},
+		{"create without id", http.MethodPost, "/api/v1/bidprentjes", `{"achternaam": "Peeters"}`, http.StatusBadRequest, "id is required"},
+		{"create with invalid JSON", http.MethodPost, "/api/v1/bidprentjes", `{"id": `, http.StatusBadRequest, "invalid request body: unexpected EOF"},
+		{"create dying before birth", http.MethodPost, "/api/v1/bidprentjes", `{"id": "3", "geboortedatum": "1900-01-01", "overlijdensdatum": "1899-01-01"}`, http.StatusBadRequest, "death date is before birth date"},
+		{"update", http.MethodPut, "/api/v1/bidprentjes/1", `{"achternaam": "Janssen"}`, http.StatusOK, ""},
+		{"update other id", http.MethodPut, "/api/v1/bidprentjes/1", `{"id": "2", "achternaam": "Janssen"}`, http.StatusBadRequest, "id in body does not match id in path"},
+		{"update missing", http.MethodPut, "/api/v1/bidprentjes/9", `{"achternaam": "Janssen"}`, http.StatusNotFound, `bidprentje "9" not found`},
+		{"delete", http.MethodDelete, "/api/v1/bidprentjes/2", "", http.StatusNoContent, ""},
+		{"delete again", http.MethodDelete, "/api/v1/bidprentjes/2", "", http.StatusNotFound, `bidprentje "2" not found`},
+		{"update deleted", http.MethodPut, "/api/v1/bidprentjes/2", `{"achternaam": "Peeters"}`, http.StatusNotFound, `bidprentje "2" not found`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if tt.error == "" {
+			continue
+		}
+		var body models.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error != tt.error {
+			t.Errorf("%s: expected error %q, got %s", tt.name, tt.error, w.Body.String())
+		}
+	}
+
+	if b, exists := s.Get("1"); !exists || b.Achternaam != "Janssen" {
+		t.Errorf("Expected 1 to be updated to Janssen, got %+v", b)
+	}
+	if _, exists := s.Get("2"); exists {
+		t.Error("Expected 2 to stay deleted")
+	}
+}
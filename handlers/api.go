@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// parsePagination reads and validates the page and page_size query parameters
+func parsePagination(c *gin.Context) (int, int, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 0, 0, fmt.Errorf("page must be a positive integer")
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		return 0, 0, fmt.Errorf("page_size must be an integer between 1 and %d", maxPageSize)
+	}
+
+	return page, pageSize, nil
+}
+
+// apiError aborts the request with a JSON error body
+func apiError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, models.ErrorResponse{Error: message})
+}
+
+// APIList returns a page of bidprentjes as JSON
+func (h *Handler) APIList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sort, err := parseSort(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, h.store.List(c.Request.Context(), page, pageSize, sort))
+}
+
+// APIGet returns a single bidprentje as JSON
+func (h *Handler) APIGet(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if id == "" {
+		apiError(c, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	b, exists := h.store.Get(id)
+	if !exists {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("bidprentje %q not found", id))
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+// APISearch runs a search and returns the paginated results as JSON
+func (h *Handler) APISearch(c *gin.Context) {
+	params, err := parseSearchCriteria(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !params.HasCriteria() {
+		apiError(c, http.StatusBadRequest, "query or at least one search field is required")
+		return
+	}
+
+	params.Page, params.PageSize, err = parsePagination(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.store.Search(c.Request.Context(), params)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "search failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// APISuggest returns completions for a typed prefix of a surname, first
+// name or place
+func (h *Handler) APISuggest(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		apiError(c, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	field := c.Query("field")
+	switch field {
+	case "", models.SuggestAchternaam, models.SuggestVoornaam, models.SuggestPlaats:
+	default:
+		apiError(c, http.StatusBadRequest, fmt.Sprintf("field must be %s, %s or %s",
+			models.SuggestAchternaam, models.SuggestVoornaam, models.SuggestPlaats))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSuggestLimit)))
+	if err != nil || limit < 1 || limit > maxSuggestLimit {
+		apiError(c, http.StatusBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxSuggestLimit))
+		return
+	}
+
+	suggestions, err := h.store.Suggest(c.Request.Context(), prefix, field, limit)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Suggest failed", "query", prefix, "error", err)
+		apiError(c, http.StatusInternalServerError, "suggest failed")
+		return
+	}
+
+	// Completions change rarely, let browsers reuse them while typing
+	c.Header("Cache-Control", "public, max-age=60")
+
+	// Browsers using the OpenSearch description expect the query and a
+	// list of completions
+	if c.Query("format") == "opensearch" {
+		values := make([]string, 0, len(suggestions))
+		for _, suggestion := range suggestions {
+			values = append(values, suggestion.Value)
+		}
+		c.Header("Content-Type", "application/x-suggestions+json; charset=utf-8")
+		c.Render(http.StatusOK, render.JSON{Data: []interface{}{prefix, values}})
+		return
+	}
+	c.JSON(http.StatusOK, models.SuggestResponse{Query: prefix, Suggestions: suggestions})
+}
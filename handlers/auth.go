@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth protects admin routes with HTTP basic authentication.
+// When no password is configured all admin requests are refused.
+func AdminAuth(username, password string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if password == "" {
+			apiError(c, http.StatusForbidden, "admin access is not configured")
+			return
+		}
+
+		user, pass, ok := c.Request.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="Bidprentjes admin", charset="UTF-8"`)
+			apiError(c, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		c.Set(gin.AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// ExplainAuth runs adminAuth for requests that ask for score explanations
+// with explain=true, and lets all other requests through
+func ExplainAuth(adminAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if explain, _ := strconv.ParseBool(c.Query("explain")); explain {
+			adminAuth(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request passed AdminAuth
+func isAdmin(c *gin.Context) bool {
+	_, ok := c.Get(gin.AuthUserKey)
+	return ok
+}
+
+// SameOrigin refuses state-changing requests that a browser sends from
+// another site. Browsers add the admin's basic credentials to cross-site
+// form posts too, so without this check any page could make a logged-in
+// admin change or delete records. Requests without Origin and Referer, as
+// sent by scripts, are let through.
+func (h *Handler) SameOrigin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		source := c.GetHeader("Origin")
+		if source == "" {
+			source = c.GetHeader("Referer")
+		}
+		if c.GetHeader("Sec-Fetch-Site") == "cross-site" || (source != "" && !h.sameSite(c, source)) {
+			apiError(c, http.StatusForbidden, "cross-site requests are not allowed")
+			return
+		}
+		c.Next()
+	}
+}
+
+// sameSite reports whether the origin or referer source has the host the
+// request was sent to, or the host of the public URL
+func (h *Handler) sameSite(c *gin.Context, source string) bool {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == c.Request.Host {
+		return true
+	}
+	public, err := url.Parse(h.siteURL(c))
+	return err == nil && u.Host == public.Host
+}
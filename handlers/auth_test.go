@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		user, pass string
+		basic      bool
+		status     int
+	}{
+		{"not configured", "", "admin", "", true, http.StatusForbidden},
+		{"no credentials", "secret", "", "", false, http.StatusUnauthorized},
+		{"wrong user", "secret", "root", "secret", true, http.StatusUnauthorized},
+		{"wrong password", "secret", "admin", "wrong", true, http.StatusUnauthorized},
+		{"password prefix", "secret", "admin", "secre", true, http.StatusUnauthorized},
+		{"longer password", "secret", "admin", "secrets", true, http.StatusUnauthorized},
+		{"valid", "secret", "admin", "secret", true, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		r := gin.New()
+		r.GET("/admin", AdminAuth("admin", tt.password), func(c *gin.Context) {
+			if !isAdmin(c) {
+				t.Errorf("%s: expected the request to be marked as admin", tt.name)
+			}
+			c.Status(http.StatusNoContent)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if tt.basic {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if (tt.status == http.StatusUnauthorized) != (challenge != "") {
+			t.Errorf("%s: unexpected WWW-Authenticate header %q", tt.name, challenge)
+		}
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	h := NewHandler(nil, "", "https://bidprentjes.example.org", DefaultMaxExport, "")
+	r := gin.New()
+	r.Use(h.SameOrigin())
+	r.Any("/admin/bidprentjes", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"read from another site", http.MethodGet, map[string]string{"Origin": "https://evil.example.com", "Sec-Fetch-Site": "cross-site"}, http.StatusNoContent},
+		{"script without headers", http.MethodPost, nil, http.StatusNoContent},
+		{"same host", http.MethodPost, map[string]string{"Origin": "http://localhost:8080"}, http.StatusNoContent},
+		{"public URL", http.MethodPost, map[string]string{"Origin": "https://bidprentjes.example.org"}, http.StatusNoContent},
+		{"referer of same host", http.MethodDelete, map[string]string{"Referer": "http://localhost:8080/admin/search?q=jan"}, http.StatusNoContent},
+		{"other origin", http.MethodPost, map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"other referer", http.MethodPut, map[string]string{"Referer": "https://evil.example.com/form"}, http.StatusForbidden},
+		{"host as subdomain", http.MethodPost, map[string]string{"Origin": "http://localhost:8080.evil.example.com"}, http.StatusForbidden},
+		{"opaque origin", http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"cross-site fetch", http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"same-origin fetch", http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://localhost:8080"}, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://localhost:8080/admin/bidprentjes", nil)
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+}
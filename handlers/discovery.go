@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sitemapNamespace    = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	openSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+
+	// sitemapSize is the number of records in one sitemap, the maximum the
+	// sitemap protocol allows
+	sitemapSize = 50000
+)
+
+type sitemapIndex struct {
+	XMLName  xml.Name         `xml:"sitemapindex"`
+	Xmlns    string           `xml:"xmlns,attr"`
+	Sitemaps []sitemapPointer `xml:"sitemap"`
+}
+
+type sitemapPointer struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns         string          `xml:"xmlns,attr"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Language      string          `xml:"Language"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Method   string `xml:"method,attr,omitempty"`
+	Template string `xml:"template,attr"`
+}
+
+// jsonLDPerson is the schema.org description of the deceased on the
+// record page
+type jsonLDPerson struct {
+	Context          string       `json:"@context"`
+	Type             string       `json:"@type"`
+	ID               string       `json:"@id"`
+	URL              string       `json:"url"`
+	Name             string       `json:"name"`
+	GivenName        string       `json:"givenName,omitempty"`
+	FamilyName       string       `json:"familyName,omitempty"`
+	BirthDate        string       `json:"birthDate,omitempty"`
+	BirthPlace       *jsonLDPlace `json:"birthPlace,omitempty"`
+	DeathDate        string       `json:"deathDate,omitempty"`
+	DeathPlace       *jsonLDPlace `json:"deathPlace,omitempty"`
+	Image            []string     `json:"image,omitempty"`
+	SubjectOf        jsonLDSource `json:"subjectOf"`
+	MainEntityOfPage string       `json:"mainEntityOfPage"`
+}
+
+type jsonLDPlace struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// jsonLDSource is the bidprentje itself, an archive component
+type jsonLDSource struct {
+	Type       string `json:"@type"`
+	Name       string `json:"name"`
+	Identifier string `json:"identifier"`
+}
+
+// recordJSONLD describes the deceased of b as a schema.org Person
+func (h *Handler) recordJSONLD(b *models.Bidprentje, permalink string) jsonLDPerson {
+	return jsonLDPerson{
+		Context:          "https://schema.org",
+		Type:             "Person",
+		ID:               permalink + "#person",
+		URL:              permalink,
+		Name:             displayName(b),
+		GivenName:        strings.TrimSpace(b.Voornaam),
+		FamilyName:       strings.Join(strings.Fields(b.Tussenvoegsel+" "+b.Achternaam), " "),
+		BirthDate:        exportDate(b.Geboortedatum),
+		BirthPlace:       jsonLDPlaceOf(b.Geboorteplaats),
+		DeathDate:        exportDate(b.Overlijdensdatum),
+		DeathPlace:       jsonLDPlaceOf(b.Overlijdensplaats),
+		Image:            h.scanURLs(b),
+		MainEntityOfPage: permalink,
+		SubjectOf: jsonLDSource{
+			Type:       "ArchiveComponent",
+			Name:       strings.TrimSpace("Bidprentje " + displayName(b)),
+			Identifier: b.ID,
+		},
+	}
+}
+
+// jsonLDPlaceOf returns the place called name, nil when it is unknown
+func jsonLDPlaceOf(name string) *jsonLDPlace {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	return &jsonLDPlace{Type: "Place", Name: name}
+}
+
+// sitemapPages is the sitemap with the pages that are not records, kept
+// apart so the record sitemaps can hold sitemapSize records each
+const sitemapPages = "pages.xml"
+
+// Sitemap returns the sitemap index, which points at the sitemap of the
+// other pages and one sitemap per sitemapSize records
+func (h *Handler) Sitemap(c *gin.Context) {
+	_, total, err := h.store.SitemapPage(1, 1)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Sitemap failed", "error", err)
+		c.String(http.StatusInternalServerError, "sitemap failed")
+		return
+	}
+
+	siteURL := h.siteURL(c)
+	index := sitemapIndex{Xmlns: sitemapNamespace}
+	index.Sitemaps = append(index.Sitemaps, sitemapPointer{Loc: siteURL + "/sitemap/" + sitemapPages})
+	for page := 1; (page-1)*sitemapSize < total; page++ {
+		index.Sitemaps = append(index.Sitemaps, sitemapPointer{Loc: fmt.Sprintf("%s/sitemap/%d.xml", siteURL, page)})
+	}
+	writeXML(c, "application/xml; charset=utf-8", index)
+}
+
+// SitemapPage returns one sitemap with the record pages of sitemapSize
+// records, or the sitemap of the other pages
+func (h *Handler) SitemapPage(c *gin.Context) {
+	if c.Param("page") == sitemapPages {
+		urlSet := sitemapURLSet{Xmlns: sitemapNamespace}
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: h.siteURL(c) + "/search"})
+		writeXML(c, "application/xml; charset=utf-8", urlSet)
+		return
+	}
+
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		c.String(http.StatusNotFound, "sitemap not found")
+		return
+	}
+
+	entries, total, err := h.store.SitemapPage(page, sitemapSize)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Sitemap failed", "error", err)
+		c.String(http.StatusInternalServerError, "sitemap failed")
+		return
+	}
+	if (page-1)*sitemapSize >= total {
+		c.String(http.StatusNotFound, "sitemap not found")
+		return
+	}
+
+	siteURL := h.siteURL(c)
+	urlSet := sitemapURLSet{Xmlns: sitemapNamespace}
+	for _, entry := range entries {
+		url := sitemapURL{Loc: siteURL + h.permalinkPath(entry.ID)}
+		if !entry.LastModified.IsZero() {
+			url.LastMod = entry.LastModified.UTC().Format(oaiDatestamp)
+		}
+		urlSet.URLs = append(urlSet.URLs, url)
+	}
+	writeXML(c, "application/xml; charset=utf-8", urlSet)
+}
+
+// Robots returns robots.txt, which points crawlers at the sitemap
+func (h *Handler) Robots(c *gin.Context) {
+	c.String(http.StatusOK, "User-agent: *\nDisallow: /admin/\nDisallow: /api/\n\nSitemap: %s/sitemap.xml\n", h.siteURL(c))
+}
+
+// OpenSearch returns the OpenSearch description document, with which
+// browsers can add the site as a search engine
+func (h *Handler) OpenSearch(c *gin.Context) {
+	siteURL := h.siteURL(c)
+	description := openSearchDescription{
+		Xmlns:         openSearchNamespace,
+		ShortName:     "Bidprentjes",
+		Description:   "Search the collection of bidprentjes (memorial cards) by name, place or year",
+		InputEncoding: "UTF-8",
+		Language:      "nl",
+		URLs: []openSearchURL{
+			{Type: "text/html", Method: "get", Template: siteURL + "/search?query={searchTerms}&page={startPage?}"},
+			{Type: "application/x-suggestions+json", Method: "get", Template: siteURL + "/api/v1/suggest?q={searchTerms}&format=opensearch"},
+			{Type: "application/opensearchdescription+xml", Rel: "self", Template: siteURL + "/opensearch.xml"},
+		},
+	}
+	writeXML(c, "application/opensearchdescription+xml; charset=utf-8", description)
+}
+
+// writeXML writes v as an XML document of contentType
+func writeXML(c *gin.Context, contentType string, v interface{}) {
+	output, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "XML response failed", "error", err)
+		c.String(http.StatusInternalServerError, "XML response failed")
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), output...))
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSitemapBoundary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("indexes more than 50,000 records")
+	}
+	h, s := newTestHandler(t)
+
+	// One record more than fits in a sitemap
+	for start := 1; start <= sitemapSize+1; start += 1000 {
+		var bidprentjes []*models.Bidprentje
+		for i := start; i < start+1000 && i <= sitemapSize+1; i++ {
+			bidprentjes = append(bidprentjes, &models.Bidprentje{ID: fmt.Sprintf("%06d", i), Achternaam: "Jansen"})
+		}
+		if err := s.BatchCreate(bidprentjes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.GET("/sitemap.xml", h.Sitemap)
+	r.GET("/sitemap/:page", h.SitemapPage)
+	get := func(path string, v interface{}) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code == http.StatusOK {
+			if err := xml.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+		}
+		return w.Code
+	}
+
+	var index sitemapIndex
+	get("/sitemap.xml", &index)
+	var locs []string
+	for _, sitemap := range index.Sitemaps {
+		locs = append(locs, sitemap.Loc)
+	}
+	want := []string{"https://example.org/sitemap/pages.xml", "https://example.org/sitemap/1.xml", "https://example.org/sitemap/2.xml"}
+	if fmt.Sprint(locs) != fmt.Sprint(want) {
+		t.Errorf("Expected sitemaps %v, got %v", want, locs)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		urls   int
+		first  string
+	}{
+		{"/sitemap/pages.xml", http.StatusOK, 1, "https://example.org/search"},
+		{"/sitemap/1.xml", http.StatusOK, sitemapSize, "https://example.org/bidprentje/000001"},
+		{"/sitemap/2.xml", http.StatusOK, 1, fmt.Sprintf("https://example.org/bidprentje/%06d", sitemapSize+1)},
+		{"/sitemap/3.xml", http.StatusNotFound, 0, ""},
+		{"/sitemap/0.xml", http.StatusNotFound, 0, ""},
+	}
+	for _, tt := range tests {
+		var urlSet sitemapURLSet
+		if status := get(tt.path, &urlSet); status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, status)
+			continue
+		}
+		if len(urlSet.URLs) != tt.urls {
+			t.Errorf("%s: expected %d URLs, got %d", tt.path, tt.urls, len(urlSet.URLs))
+		} else if tt.urls > 0 && urlSet.URLs[0].Loc != tt.first {
+			t.Errorf("%s: expected %s first, got %s", tt.path, tt.first, urlSet.URLs[0].Loc)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"bidprentjes-api/a2a"
+	"bidprentjes-api/models"
+	"bidprentjes-api/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxExport is the maximum number of records in one export when
+// MAX_EXPORT_RECORDS is not set
+const DefaultMaxExport = 100000
+
+const (
+	exportCSV     = "csv"
+	exportJSONL   = "jsonl"
+	exportXLSX    = "xlsx"
+	exportGEDCOM  = "gedcom"
+	exportGEDCOM7 = "gedcom7"
+	exportA2A     = "a2a"
+)
+
+// exportContentTypes maps the export formats to their media types
+var exportContentTypes = map[string]string{
+	exportCSV:     "text/csv; charset=utf-8",
+	exportJSONL:   "application/x-ndjson",
+	exportXLSX:    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	exportGEDCOM:  "application/x-gedcom; charset=utf-8",
+	exportGEDCOM7: "text/vnd.familysearch.gedcom",
+	exportA2A:     "application/xml; charset=utf-8",
+}
+
+// exportExtensions maps the export formats to their file extensions
+var exportExtensions = map[string]string{
+	exportCSV:     "csv",
+	exportJSONL:   "jsonl",
+	exportXLSX:    "xlsx",
+	exportGEDCOM:  "ged",
+	exportGEDCOM7: "ged",
+	exportA2A:     "xml",
+}
+
+// exportHeader names the columns of a spreadsheet export. The first nine
+// are the columns of the CSV import.
+var exportHeader = []string{
+	"id", "voornaam", "tussenvoegsel", "achternaam", "geboortedatum",
+	"geboorteplaats", "overlijdensdatum", "overlijdensplaats", "photo", "scans",
+}
+
+// exportRecord is a bidprentje as written to a JSON Lines export
+type exportRecord struct {
+	ID                string   `json:"id"`
+	Voornaam          string   `json:"voornaam"`
+	Tussenvoegsel     string   `json:"tussenvoegsel"`
+	Achternaam        string   `json:"achternaam"`
+	Geboortedatum     string   `json:"geboortedatum,omitempty"`
+	Geboorteplaats    string   `json:"geboorteplaats"`
+	Overlijdensdatum  string   `json:"overlijdensdatum,omitempty"`
+	Overlijdensplaats string   `json:"overlijdensplaats"`
+	Photo             bool     `json:"photo"`
+	Scans             []string `json:"scans"`
+	ScanURLs          []string `json:"scan_urls"`
+}
+
+// exportWriter writes the records of one export format
+type exportWriter interface {
+	Write(b *models.Bidprentje) error
+	Close() error
+}
+
+// Export streams every bidprentje matching the search parameters of
+// WebSearch in one of the export formats
+func (h *Handler) Export(c *gin.Context) {
+	params, err := parseSearchCriteria(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filename := "bidprentjes-" + time.Now().Format("20060102")
+	h.streamExport(c, filename, false, func(visit func(*models.Bidprentje) error) error {
+		return h.store.Export(c.Request.Context(), params, h.maxExport, visit)
+	})
+}
+
+// ExportBidprentje exports a single bidprentje in one of the export
+// formats, e.g. as GEDCOM for family-tree software
+func (h *Handler) ExportBidprentje(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	b, exists := h.store.Get(id)
+	if !exists {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("bidprentje %q not found", id))
+		return
+	}
+
+	filename := "bidprentje-" + strings.Map(func(r rune) rune {
+		if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '_'
+	}, b.ID)
+	h.streamExport(c, filename, true, func(visit func(*models.Bidprentje) error) error {
+		return visit(b)
+	})
+}
+
+// streamExport writes the records that export passes to visit in the
+// format of the format query parameter. The CSV uses the layout of the CSV
+// import, without a header, so it can be uploaded again; scan_links=true
+// adds the scan links as a tenth column. A single record is exported as a
+// bare A2A document, more records are wrapped in an A2ACollection.
+func (h *Handler) streamExport(c *gin.Context, filename string, single bool, export func(visit func(*models.Bidprentje) error) error) {
+	format := c.DefaultQuery("format", exportCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		apiError(c, http.StatusBadRequest, fmt.Sprintf("format must be %s, %s, %s, %s, %s or %s", exportCSV, exportJSONL, exportXLSX, exportGEDCOM, exportGEDCOM7, exportA2A))
+		return
+	}
+
+	scanLinks := false
+	if v := c.Query("scan_links"); v != "" {
+		var err error
+		if scanLinks, err = strconv.ParseBool(v); err != nil {
+			apiError(c, http.StatusBadRequest, "scan_links must be true or false")
+			return
+		}
+	}
+
+	// Large exports outlive the server's default write timeout, which would
+	// cut them off after the status has been sent
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	// The response is only started with the first record, so a too large
+	// export can still be refused with an error
+	var w exportWriter
+	start := func() error {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, exportExtensions[format]))
+		c.Status(http.StatusOK)
+
+		siteURL := h.siteURL(c)
+		permalink := func(id string) string {
+			return siteURL + h.permalinkPath(id)
+		}
+
+		switch format {
+		case exportJSONL:
+			w = newJSONLExportWriter(c.Writer, h.scanURLs)
+		case exportXLSX:
+			xw, err := newXLSXExportWriter(c.Writer, h.scanURLs)
+			if err != nil {
+				return err
+			}
+			w = xw
+		case exportGEDCOM, exportGEDCOM7:
+			version := gedcom551
+			if format == exportGEDCOM7 {
+				version = gedcom7
+			}
+			gw, err := newGEDCOMWriter(c.Writer, version, siteURL, permalink, h.scanURLs)
+			if err != nil {
+				return err
+			}
+			w = gw
+		case exportA2A:
+			aw, err := newA2AExportWriter(c.Writer, !single, a2a.Options{Permalink: permalink, ScanURLs: h.scanURLs})
+			if err != nil {
+				return err
+			}
+			w = aw
+		default:
+			w = newCSVExportWriter(c.Writer, h.scanURLs, scanLinks)
+		}
+		return nil
+	}
+
+	count := 0
+	startTime := time.Now()
+	err := export(func(b *models.Bidprentje) error {
+		if w == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		count++
+		return w.Write(b)
+	})
+	if errors.Is(err, store.ErrExportTooLarge) {
+		apiError(c, http.StatusBadRequest, err.Error()+"; narrow the search")
+		return
+	}
+	if err == nil && w == nil {
+		err = start()
+	}
+	if err != nil {
+		if w == nil {
+			apiError(c, http.StatusInternalServerError, "export failed")
+			return
+		}
+		// The response has already started, all we can do is cut it off
+		slog.ErrorContext(c.Request.Context(), "Export failed", "format", format, "records", count, "error", err)
+		c.Abort()
+		return
+	}
+
+	if err := w.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Export failed", "format", format, "records", count, "error", err)
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "Exported records", "format", format, "records", count, "duration", time.Since(startTime))
+}
+
+// scanURL returns the CDN link of a scan
+func (h *Handler) scanURL(scan string) string {
+	return h.cdnBaseURL + "/" + scan + ".jpg"
+}
+
+// scanURLs returns the CDN links of all scans of b
+func (h *Handler) scanURLs(b *models.Bidprentje) []string {
+	urls := make([]string, 0, len(b.Scans))
+	for _, scan := range b.Scans {
+		urls = append(urls, h.scanURL(scan))
+	}
+	return urls
+}
+
+// exportDate formats a date like the CSV import expects, empty when unknown
+func exportDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// exportRow returns the nine CSV import columns of b
+func exportRow(b *models.Bidprentje) []string {
+	return []string{
+		b.ID,
+		b.Voornaam,
+		b.Tussenvoegsel,
+		b.Achternaam,
+		exportDate(b.Geboortedatum),
+		b.Geboorteplaats,
+		exportDate(b.Overlijdensdatum),
+		b.Overlijdensplaats,
+		strconv.FormatBool(b.Photo),
+	}
+}
+
+type csvExportWriter struct {
+	csv       *csv.Writer
+	scanURLs  func(*models.Bidprentje) []string
+	scanLinks bool
+}
+
+func newCSVExportWriter(w http.ResponseWriter, scanURLs func(*models.Bidprentje) []string, scanLinks bool) *csvExportWriter {
+	return &csvExportWriter{csv: csv.NewWriter(w), scanURLs: scanURLs, scanLinks: scanLinks}
+}
+
+func (e *csvExportWriter) Write(b *models.Bidprentje) error {
+	row := exportRow(b)
+	if e.scanLinks {
+		row = append(row, strings.Join(e.scanURLs(b), " "))
+	}
+	return e.csv.Write(row)
+}
+
+func (e *csvExportWriter) Close() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+type jsonlExportWriter struct {
+	buf      *bufio.Writer
+	encoder  *json.Encoder
+	scanURLs func(*models.Bidprentje) []string
+}
+
+func newJSONLExportWriter(w http.ResponseWriter, scanURLs func(*models.Bidprentje) []string) *jsonlExportWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlExportWriter{buf: buf, encoder: json.NewEncoder(buf), scanURLs: scanURLs}
+}
+
+func (e *jsonlExportWriter) Write(b *models.Bidprentje) error {
+	scans := b.Scans
+	if scans == nil {
+		scans = []string{}
+	}
+	return e.encoder.Encode(exportRecord{
+		ID:                b.ID,
+		Voornaam:          b.Voornaam,
+		Tussenvoegsel:     b.Tussenvoegsel,
+		Achternaam:        b.Achternaam,
+		Geboortedatum:     exportDate(b.Geboortedatum),
+		Geboorteplaats:    b.Geboorteplaats,
+		Overlijdensdatum:  exportDate(b.Overlijdensdatum),
+		Overlijdensplaats: b.Overlijdensplaats,
+		Photo:             b.Photo,
+		Scans:             scans,
+		ScanURLs:          e.scanURLs(b),
+	})
+}
+
+func (e *jsonlExportWriter) Close() error {
+	return e.buf.Flush()
+}
+
+type xlsxExportWriter struct {
+	xlsx     *xlsxWriter
+	buf      *bufio.Writer
+	scanURLs func(*models.Bidprentje) []string
+}
+
+func newXLSXExportWriter(w http.ResponseWriter, scanURLs func(*models.Bidprentje) []string) (*xlsxExportWriter, error) {
+	buf := bufio.NewWriter(w)
+	xw, err := newXLSXWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	if err := xw.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &xlsxExportWriter{xlsx: xw, buf: buf, scanURLs: scanURLs}, nil
+}
+
+func (e *xlsxExportWriter) Write(b *models.Bidprentje) error {
+	return e.xlsx.Write(append(exportRow(b), strings.Join(e.scanURLs(b), " ")))
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if err := e.xlsx.Close(); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+// a2aExportWriter writes bidprentjes as A2A documents
+type a2aExportWriter struct {
+	buf        *bufio.Writer
+	collection bool
+	opts       a2a.Options
+}
+
+func newA2AExportWriter(w http.ResponseWriter, collection bool, opts a2a.Options) (*a2aExportWriter, error) {
+	buf := bufio.NewWriter(w)
+	buf.WriteString(xml.Header)
+	if collection {
+		fmt.Fprintf(buf, "<A2ACollection xmlns=%q>\n", a2a.Namespace)
+	}
+	return &a2aExportWriter{buf: buf, collection: collection, opts: opts}, buf.Flush()
+}
+
+func (e *a2aExportWriter) Write(b *models.Bidprentje) error {
+	if err := a2a.Encode(e.buf, a2a.FromBidprentje(b, e.opts)); err != nil {
+		return err
+	}
+	_, err := e.buf.WriteString("\n")
+	return err
+}
+
+func (e *a2aExportWriter) Close() error {
+	if e.collection {
+		e.buf.WriteString("</A2ACollection>\n")
+	}
+	return e.buf.Flush()
+}
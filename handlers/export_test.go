@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportOutlivesWriteTimeout(t *testing.T) {
+	h, s := newTestHandler(t)
+
+	// More than one page of the store's export
+	const records = 2500
+	var bidprentjes []*models.Bidprentje
+	for i := 1; i <= records; i++ {
+		bidprentjes = append(bidprentjes, &models.Bidprentje{ID: fmt.Sprintf("%05d", i), Voornaam: "Jan", Achternaam: "Jansen"})
+	}
+	if err := s.BatchCreate(bidprentjes); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/export", h.Export)
+	server := httptest.NewUnstartedServer(r)
+	// A write timeout that has passed before the export starts
+	server.Config.WriteTimeout = time.Nanosecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/export?format=csv&achternaam=jansen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != records {
+		t.Fatalf("Expected %d records, got %d", records, len(rows))
+	}
+	if rows[0][0] != "00001" || rows[records-1][0] != fmt.Sprintf("%05d", records) {
+		t.Errorf("Expected records 00001 to %05d, got %s to %s", records, rows[0][0], rows[len(rows)-1][0])
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/translations"
+)
+
+// facetChip is a clickable filter on the search page
+type facetChip struct {
+	Label  string
+	Count  int
+	URL    template.URL
+	Active bool
+}
+
+// facetGroup is a titled row of filter chips
+type facetGroup struct {
+	Title string
+	Chips []facetChip
+}
+
+// facetGroups turns the facet counts into filter chips. A chip adds its
+// filter to the current search, or removes it again when already active.
+// Place chips filter on the exact place, not through the fuzzy place
+// fields, so they find as many records as they count.
+func facetGroups(facets *models.Facets, form url.Values, t translations.Translations) []facetGroup {
+	if facets == nil {
+		return nil
+	}
+
+	valueFilter := func(param string) func(string) url.Values {
+		return func(value string) url.Values {
+			return url.Values{param: {value}}
+		}
+	}
+	decadeFilter := func(fromParam, toParam string) func(string) url.Values {
+		return func(value string) url.Values {
+			start, _ := strconv.Atoi(value)
+			return url.Values{fromParam: {value}, toParam: {strconv.Itoa(start + 9)}}
+		}
+	}
+	decadeLabel := func(value string) string {
+		start, _ := strconv.Atoi(value)
+		return fmt.Sprintf("%d–%d", start, start+9)
+	}
+	boolLabel := func(value string) string {
+		if value == "true" {
+			return t.Yes
+		}
+		return t.No
+	}
+	plainLabel := func(value string) string { return value }
+
+	specs := []struct {
+		title  string
+		counts []models.FacetCount
+		filter func(string) url.Values
+		label  func(string) string
+	}{
+		{t.BirthPlace, facets.Geboorteplaats, valueFilter("geboorteplaats_filter"), plainLabel},
+		{t.DeathPlace, facets.Overlijdensplaats, valueFilter("overlijdensplaats_filter"), plainLabel},
+		{t.BirthDecade, facets.Geboortedecennium, decadeFilter("geboortejaar_van", "geboortejaar_tot"), decadeLabel},
+		{t.DeathDecade, facets.Overlijdensdecennium, decadeFilter("overlijdensjaar_van", "overlijdensjaar_tot"), decadeLabel},
+		{t.HasPhoto, facets.Photo, valueFilter("has_photo"), boolLabel},
+		{t.HasScans, facets.HasScans, valueFilter("has_scans"), boolLabel},
+	}
+
+	var groups []facetGroup
+	for _, spec := range specs {
+		if len(spec.counts) == 0 {
+			continue
+		}
+		group := facetGroup{Title: spec.title}
+		for _, count := range spec.counts {
+			filter := spec.filter(count.Value)
+			group.Chips = append(group.Chips, facetChip{
+				Label:  spec.label(count.Value),
+				Count:  count.Count,
+				URL:    template.URL(facetURL(form, filter).Encode()),
+				Active: filterActive(form, filter),
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// filterActive reports whether all parameters of filter are set in form
+func filterActive(form url.Values, filter url.Values) bool {
+	for key := range filter {
+		if form.Get(key) != filter.Get(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// facetURL returns the query of the current search with filter toggled,
+// starting again at the first page
+func facetURL(form url.Values, filter url.Values) url.Values {
+	active := filterActive(form, filter)
+	query := url.Values{}
+	for key, values := range form {
+		if key != "page" && key != "status" {
+			query[key] = values
+		}
+	}
+	for key := range filter {
+		if active {
+			query.Del(key)
+		} else {
+			query.Set(key, filter.Get(key))
+		}
+	}
+	return query
+}
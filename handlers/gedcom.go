@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+)
+
+const (
+	gedcom551 = "5.5.1"
+	gedcom7   = "7.0"
+
+	// gedcomLineLength is the longest value written on one line in GEDCOM
+	// 5.5.1; longer values are continued with CONC, as it limits lines to
+	// 255 characters. GEDCOM 7 has no line limit and no CONC.
+	gedcomLineLength = 200
+)
+
+// gedcomMonths are the month abbreviations of GEDCOM dates
+var gedcomMonths = [...]string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// gedcomWriter writes bidprentjes as INDI records of a GEDCOM 5.5.1 or 7
+// file. All individuals cite a single SOUR record for the collection, with
+// the permalink of their bidprentje as the page.
+type gedcomWriter struct {
+	buf       *bufio.Writer
+	version   string
+	permalink func(id string) string
+	scanURLs  func(*models.Bidprentje) []string
+	people    int
+	objects   int
+}
+
+// newGEDCOMWriter writes the header, submitter and source records
+func newGEDCOMWriter(w io.Writer, version string, siteURL string, permalink func(string) string, scanURLs func(*models.Bidprentje) []string) (*gedcomWriter, error) {
+	g := &gedcomWriter{
+		buf:       bufio.NewWriter(w),
+		version:   version,
+		permalink: permalink,
+		scanURLs:  scanURLs,
+	}
+
+	if version == gedcom7 {
+		// GEDCOM 7 files are always UTF-8 and may start with a BOM
+		g.buf.WriteString("\ufeff")
+	}
+	g.record("", "HEAD")
+	g.tag(1, "GEDC")
+	g.line(2, "VERS", version)
+	if version == gedcom551 {
+		g.line(2, "FORM", "LINEAGE-LINKED")
+		g.line(1, "CHAR", "UTF-8")
+	}
+	g.line(1, "SOUR", "BIDPRENTJES")
+	g.line(2, "NAME", "Bidprentjes")
+	g.line(1, "DATE", gedcomDate(time.Now()))
+	g.pointer(1, "SUBM", "@SUBM1@")
+
+	g.record("@SUBM1@", "SUBM")
+	g.line(1, "NAME", "Bidprentjes")
+
+	g.record("@SOUR1@", "SOUR")
+	g.line(1, "TITL", "Bidprentjes")
+	g.line(1, "PUBL", siteURL)
+
+	return g, g.buf.Flush()
+}
+
+// Write adds an INDI record for b, and in GEDCOM 7 an OBJE record per scan
+func (g *gedcomWriter) Write(b *models.Bidprentje) error {
+	g.people++
+	g.record(fmt.Sprintf("@I%d@", g.people), "INDI")
+
+	surname := strings.TrimSpace(b.Achternaam)
+	g.line(1, "NAME", fmt.Sprintf("%s /%s/", b.Voornaam, strings.TrimSpace(b.Tussenvoegsel+" "+surname)))
+	g.line(2, "GIVN", b.Voornaam)
+	g.line(2, "SPFX", b.Tussenvoegsel)
+	g.line(2, "SURN", surname)
+
+	g.event("BIRT", b.Geboortedatum, b.Geboorteplaats)
+	g.event("DEAT", b.Overlijdensdatum, b.Overlijdensplaats)
+
+	g.line(1, "REFN", b.ID)
+	g.line(2, "TYPE", "bidprentje")
+
+	scans := g.scanURLs(b)
+	var objects []string
+	for _, url := range scans {
+		if g.version == gedcom551 {
+			g.tag(1, "OBJE")
+			g.line(2, "FILE", url)
+			g.line(3, "FORM", "jpg")
+			g.line(4, "MEDI", "photo")
+			continue
+		}
+		// GEDCOM 7 no longer allows embedded multimedia
+		g.objects++
+		xref := fmt.Sprintf("@O%d@", g.objects)
+		objects = append(objects, xref)
+		g.pointer(1, "OBJE", xref)
+	}
+
+	g.pointer(1, "SOUR", "@SOUR1@")
+	g.line(2, "PAGE", g.permalink(b.ID))
+
+	for i, xref := range objects {
+		g.record(xref, "OBJE")
+		g.line(1, "FILE", scans[i])
+		g.line(2, "FORM", "image/jpeg")
+		g.line(3, "MEDI", "PHOTO")
+	}
+
+	return g.buf.Flush()
+}
+
+// Close writes the trailer
+func (g *gedcomWriter) Close() error {
+	g.record("", "TRLR")
+	return g.buf.Flush()
+}
+
+// event writes a BIRT or DEAT event when its date or place is known
+func (g *gedcomWriter) event(tag string, date time.Time, place string) {
+	place = strings.TrimSpace(place)
+	if date.IsZero() && place == "" {
+		return
+	}
+	g.tag(1, tag)
+	if !date.IsZero() {
+		g.line(2, "DATE", gedcomDate(date))
+	}
+	g.line(2, "PLAC", place)
+}
+
+// record starts a level 0 record
+func (g *gedcomWriter) record(xref, tag string) {
+	g.write(0, xref, tag, "")
+}
+
+// pointer writes a line that refers to the record xref
+func (g *gedcomWriter) pointer(level int, tag, xref string) {
+	g.write(level, "", tag, xref)
+}
+
+// tag writes a line without a value, like BIRT
+func (g *gedcomWriter) tag(level int, tag string) {
+	g.write(level, "", tag, "")
+}
+
+// line writes a line with a text value, in GEDCOM 5.5.1 continued with
+// CONC lines when it is too long. Lines with an empty value are left out.
+func (g *gedcomWriter) line(level int, tag, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return
+	}
+	value = g.escape(value)
+	if g.version != gedcom551 {
+		g.write(level, "", tag, value)
+		return
+	}
+
+	first, rest := splitGEDCOMValue(value)
+	g.write(level, "", tag, first)
+	for rest != "" {
+		var part string
+		part, rest = splitGEDCOMValue(rest)
+		g.write(level+1, "", "CONC", part)
+	}
+}
+
+// write writes a single line
+func (g *gedcomWriter) write(level int, xref, tag, value string) {
+	fmt.Fprint(g.buf, level)
+	if xref != "" {
+		g.buf.WriteString(" " + xref)
+	}
+	g.buf.WriteString(" " + tag)
+	if value != "" {
+		g.buf.WriteString(" " + value)
+	}
+	g.buf.WriteString("\r\n")
+}
+
+// escape doubles @ signs in text so they are not read as pointers. GEDCOM
+// 7 only requires this for a leading @.
+func (g *gedcomWriter) escape(value string) string {
+	if g.version == gedcom551 {
+		return strings.ReplaceAll(value, "@", "@@")
+	}
+	if strings.HasPrefix(value, "@") {
+		return "@" + value
+	}
+	return value
+}
+
+// splitGEDCOMValue cuts the part of value that fits on one line. It is cut
+// between two characters that are not spaces, as CONC values may lose
+// leading and trailing spaces, and not within an escaped @@.
+func splitGEDCOMValue(value string) (string, string) {
+	if len(value) <= gedcomLineLength {
+		return value, ""
+	}
+	cut := gedcomLineLength
+	for cut > 1 && (!isUTF8Start(value[cut]) || value[cut] == ' ' || value[cut-1] == ' ' || value[cut-1] == '@') {
+		cut--
+	}
+	return value[:cut], value[cut:]
+}
+
+// isUTF8Start reports whether b starts a UTF-8 encoded character
+func isUTF8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// gedcomDate formats a date as "2 NOV 1921"
+func gedcomDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), gedcomMonths[t.Month()-1], t.Year())
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"bidprentjes-api/models"
+)
+
+// gedcomTestRecord has a place that is too long for one GEDCOM 5.5.1 line,
+// a tussenvoegsel, @ signs and two scans
+var gedcomTestRecord = &models.Bidprentje{
+	ID:                "1234",
+	Voornaam:          "Johannes @Jan",
+	Tussenvoegsel:     "van der",
+	Achternaam:        "Velden",
+	Geboortedatum:     time.Date(1880, 3, 2, 0, 0, 0, 0, time.UTC),
+	Geboorteplaats:    "@Blerick",
+	Overlijdensdatum:  time.Date(1921, 11, 2, 0, 0, 0, 0, time.UTC),
+	Overlijdensplaats: strings.Repeat("Venlo ", 40) + "Limburg",
+	Scans:             []string{"1234-1.jpg", "1234-2.jpg"},
+}
+
+func TestGEDCOMWriter(t *testing.T) {
+	longPlace := gedcomTestRecord.Overlijdensplaats
+	// GEDCOM 5.5.1 continues the place within a word, as CONC values may
+	// lose spaces at their ends
+	firstPart, rest := longPlace[:gedcomLineLength], longPlace[gedcomLineLength:]
+
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{gedcom551, []string{
+			"0 HEAD",
+			"1 GEDC",
+			"2 VERS 5.5.1",
+			"2 FORM LINEAGE-LINKED",
+			"1 CHAR UTF-8",
+			"1 SOUR BIDPRENTJES",
+			"2 NAME Bidprentjes",
+			"1 DATE <today>",
+			"1 SUBM @SUBM1@",
+			"0 @SUBM1@ SUBM",
+			"1 NAME Bidprentjes",
+			"0 @SOUR1@ SOUR",
+			"1 TITL Bidprentjes",
+			"1 PUBL https://example.org",
+			"0 @I1@ INDI",
+			"1 NAME Johannes @@Jan /van der Velden/",
+			"2 GIVN Johannes @@Jan",
+			"2 SPFX van der",
+			"2 SURN Velden",
+			"1 BIRT",
+			"2 DATE 2 MAR 1880",
+			"2 PLAC @@Blerick",
+			"1 DEAT",
+			"2 DATE 2 NOV 1921",
+			"2 PLAC " + firstPart,
+			"3 CONC " + rest,
+			"1 REFN 1234",
+			"2 TYPE bidprentje",
+			"1 OBJE",
+			"2 FILE https://example.org/scans/1234-1.jpg",
+			"3 FORM jpg",
+			"4 MEDI photo",
+			"1 OBJE",
+			"2 FILE https://example.org/scans/1234-2.jpg",
+			"3 FORM jpg",
+			"4 MEDI photo",
+			"1 SOUR @SOUR1@",
+			"2 PAGE https://example.org/bidprentje/1234",
+			"0 TRLR",
+		}},
+		{gedcom7, []string{
+			"\ufeff0 HEAD",
+			"1 GEDC",
+			"2 VERS 7.0",
+			"1 SOUR BIDPRENTJES",
+			"2 NAME Bidprentjes",
+			"1 DATE <today>",
+			"1 SUBM @SUBM1@",
+			"0 @SUBM1@ SUBM",
+			"1 NAME Bidprentjes",
+			"0 @SOUR1@ SOUR",
+			"1 TITL Bidprentjes",
+			"1 PUBL https://example.org",
+			"0 @I1@ INDI",
+			"1 NAME Johannes @Jan /van der Velden/",
+			"2 GIVN Johannes @Jan",
+			"2 SPFX van der",
+			"2 SURN Velden",
+			"1 BIRT",
+			"2 DATE 2 MAR 1880",
+			"2 PLAC @@Blerick",
+			"1 DEAT",
+			"2 DATE 2 NOV 1921",
+			"2 PLAC " + longPlace,
+			"1 REFN 1234",
+			"2 TYPE bidprentje",
+			"1 OBJE @O1@",
+			"1 OBJE @O2@",
+			"1 SOUR @SOUR1@",
+			"2 PAGE https://example.org/bidprentje/1234",
+			"0 @O1@ OBJE",
+			"1 FILE https://example.org/scans/1234-1.jpg",
+			"2 FORM image/jpeg",
+			"3 MEDI PHOTO",
+			"0 @O2@ OBJE",
+			"1 FILE https://example.org/scans/1234-2.jpg",
+			"2 FORM image/jpeg",
+			"3 MEDI PHOTO",
+			"0 TRLR",
+		}},
+	}
+
+	// The header is dated with the day of the export
+	headerDate := regexp.MustCompile(`(?m)^1 DATE \d{1,2} [A-Z]{3} \d{4}\r$`)
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			var buf bytes.Buffer
+			g, err := newGEDCOMWriter(&buf, tt.version, "https://example.org",
+				func(id string) string { return "https://example.org/bidprentje/" + id },
+				func(b *models.Bidprentje) []string {
+					var urls []string
+					for _, scan := range b.Scans {
+						urls = append(urls, "https://example.org/scans/"+scan)
+					}
+					return urls
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := g.Write(gedcomTestRecord); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := headerDate.ReplaceAllString(buf.String(), "1 DATE <today>\r")
+			want := strings.Join(tt.want, "\r\n") + "\r\n"
+			if got != want {
+				gotLines, wantLines := strings.Split(got, "\r\n"), strings.Split(want, "\r\n")
+				for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+					var gotLine, wantLine string
+					if i < len(gotLines) {
+						gotLine = gotLines[i]
+					}
+					if i < len(wantLines) {
+						wantLine = wantLines[i]
+					}
+					if gotLine != wantLine {
+						t.Fatalf("line %d: got %q, want %q", i+1, gotLine, wantLine)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"bidprentjes-api/models"
 	"bidprentjes-api/store"
@@ -14,20 +19,50 @@ import (
 type Handler struct {
 	store      *store.Store
 	cdnBaseURL string
+	publicURL  string
+	maxExport  int
+	adminEmail string
 }
 
-func NewHandler(store *store.Store, cdnBaseURL string) *Handler {
+func NewHandler(store *store.Store, cdnBaseURL, publicURL string, maxExport int, adminEmail string) *Handler {
 	return &Handler{
 		store:      store,
 		cdnBaseURL: cdnBaseURL,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		maxExport:  maxExport,
+		adminEmail: adminEmail,
 	}
 }
 
+// siteURL returns the public base URL of the site: PUBLIC_URL when set, or
+// else the scheme and host the request was made to
+func (h *Handler) siteURL(c *gin.Context) string {
+	if h.publicURL != "" {
+		return h.publicURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// permalinkPath returns the path of the permanent link to a bidprentje,
+// its record page
+func (h *Handler) permalinkPath(id string) string {
+	return "/bidprentje/" + url.PathEscape(id)
+}
+
 func (h *Handler) WebSearch(c *gin.Context) {
+	h.renderSearch(c, "/search")
+}
 
-	query := c.Query("query")
+// renderSearch renders the search page, with links pointing at searchPath
+func (h *Handler) renderSearch(c *gin.Context, searchPath string) {
 	lang := c.DefaultQuery("lang", "nl") // Default to Dutch
-	exactMatch := c.Query("exact_match") == "on"
+
+	// Invalid criteria are ignored on the web page
+	params, _ := parseSearchCriteria(c)
 
 	// Parse page and pageSize from query parameters
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -38,17 +73,38 @@ func (h *Handler) WebSearch(c *gin.Context) {
 	if err != nil || pageSize < 1 {
 		pageSize = 10
 	}
+	params.Page = page
+	params.PageSize = pageSize
 
 	var response *models.PaginatedResponse
-	if query != "" {
-		response = h.store.Search(models.SearchParams{
-			Query:      query,
-			Page:       page,
-			PageSize:   pageSize,
-			ExactMatch: exactMatch,
-		})
+	if params.HasCriteria() {
+		response, err = h.store.Search(c.Request.Context(), params)
+		if err != nil {
+			response = &models.PaginatedResponse{
+				Items:    []models.Bidprentje{},
+				Page:     page,
+				PageSize: pageSize,
+			}
+		}
 	} else {
-		response = h.store.List(page, pageSize)
+		response = h.store.List(c.Request.Context(), page, pageSize, params.Sort)
+	}
+
+	// Pagination links keep every parameter except the page itself
+	form := c.Request.URL.Query()
+	linkParams := url.Values{}
+	for key, values := range form {
+		if key != "page" && key != "status" {
+			linkParams[key] = values
+		}
+	}
+
+	// Show the order that was actually applied in the sort menu
+	sort := params.Sort
+	if !params.HasCriteria() && (sort == "" || sort == models.SortRelevance) {
+		sort = "id"
+	} else if sort == "" {
+		sort = models.SortRelevance
 	}
 
 	t := translations.GetTranslation(lang)
@@ -56,13 +112,197 @@ func (h *Handler) WebSearch(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "search.html", gin.H{
 		"data":        response,
-		"searchQuery": query,
+		"searchQuery": params.Query,
 		"lang":        lang,
 		"languages":   languages,
 		"t":           t,
 		"title":       t.Search,
 		"description": t.SearchHelp,
-		"exactMatch":  exactMatch,
-		"cdnBaseURL":  h.cdnBaseURL,
+		"matchMode":   params.Mode(),
+		"advanced":    params.HasFieldCriteria(),
+		"form":        form,
+		"linkParams":  template.URL(linkParams.Encode()),
+		"searchPath":  searchPath,
+		"admin":       isAdmin(c),
+		"status":      c.Query("status"),
+		"facets":      facetGroups(response.Facets, form, t),
+		"sort":        sort,
+		"sortOptions": sortOptions(t, params.HasCriteria()),
+		"highlights":  resultHighlights(response.Hits, t),
 	})
 }
+
+// parseSort reads and validates the sort query parameter
+func parseSort(c *gin.Context) (string, error) {
+	sort := strings.TrimSpace(c.Query("sort"))
+	if !models.ValidSort(sort) {
+		return "", fmt.Errorf("sort must be %s or one of %s, optionally prefixed with - for descending order",
+			models.SortRelevance, strings.Join(models.SortFields, ", "))
+	}
+	return sort, nil
+}
+
+// sortOption is an entry of the sort menu on the search page
+type sortOption struct {
+	Value string
+	Label string
+}
+
+// sortOptions lists the sort orders offered on the search page. Relevance
+// is only offered when there is something to search for.
+func sortOptions(t translations.Translations, scored bool) []sortOption {
+	var options []sortOption
+	if scored {
+		options = append(options, sortOption{models.SortRelevance, t.Relevance})
+	}
+
+	labels := map[string]string{
+		"achternaam":       t.LastName,
+		"voornaam":         t.FirstName,
+		"geboortedatum":    t.BirthDate,
+		"overlijdensdatum": t.DeathDate,
+		"id":               t.ID,
+	}
+	for _, field := range models.SortFields {
+		options = append(options,
+			sortOption{field, fmt.Sprintf("%s (%s)", labels[field], t.Ascending)},
+			sortOption{"-" + field, fmt.Sprintf("%s (%s)", labels[field], t.Descending)},
+		)
+	}
+	return options
+}
+
+// parseSearchCriteria reads the free-text and fielded search criteria from
+// the query string. Invalid values are left out of the returned parameters
+// and reported as the first error.
+func parseSearchCriteria(c *gin.Context) (models.SearchParams, error) {
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	params := models.SearchParams{
+		Query:             strings.TrimSpace(c.Query("query")),
+		Voornaam:          strings.TrimSpace(c.Query("voornaam")),
+		Tussenvoegsel:     strings.TrimSpace(c.Query("tussenvoegsel")),
+		Achternaam:        strings.TrimSpace(c.Query("achternaam")),
+		Geboorteplaats:    strings.TrimSpace(c.Query("geboorteplaats")),
+		Overlijdensplaats: strings.TrimSpace(c.Query("overlijdensplaats")),
+
+		GeboorteplaatsFilter:    strings.TrimSpace(c.Query("geboorteplaats_filter")),
+		OverlijdensplaatsFilter: strings.TrimSpace(c.Query("overlijdensplaats_filter")),
+	}
+
+	if v := c.Query("exact_match"); v != "" {
+		exactMatch, err := strconv.ParseBool(v)
+		if v == "on" {
+			exactMatch, err = true, nil
+		}
+		if err != nil {
+			fail(fmt.Errorf("exact_match must be true or false"))
+		}
+		params.ExactMatch = exactMatch
+	}
+
+	switch mode := c.Query("match"); mode {
+	case "", models.MatchFuzzy, models.MatchExact, models.MatchPhonetic:
+		params.MatchMode = mode
+	default:
+		fail(fmt.Errorf("match must be %s, %s or %s", models.MatchFuzzy, models.MatchExact, models.MatchPhonetic))
+	}
+
+	sort, err := parseSort(c)
+	if err != nil {
+		fail(err)
+	}
+	params.Sort = sort
+
+	// Score explanations are only shown to admins
+	if v := c.Query("explain"); v != "" && isAdmin(c) {
+		explain, err := strconv.ParseBool(v)
+		if err != nil {
+			fail(fmt.Errorf("explain must be true or false"))
+		}
+		params.Explain = explain
+	}
+
+	years := []struct {
+		name string
+		dst  *int
+	}{
+		{"geboortejaar_van", &params.GeboortejaarVan},
+		{"geboortejaar_tot", &params.GeboortejaarTot},
+		{"overlijdensjaar_van", &params.OverlijdensjaarVan},
+		{"overlijdensjaar_tot", &params.OverlijdensjaarTot},
+	}
+	for _, y := range years {
+		v := strings.TrimSpace(c.Query(y.name))
+		if v == "" {
+			continue
+		}
+		year, err := strconv.Atoi(v)
+		if err != nil || year < 1 || year > 9999 {
+			fail(fmt.Errorf("%s must be a year between 1 and 9999", y.name))
+			continue
+		}
+		*y.dst = year
+	}
+	if params.GeboortejaarVan != 0 && params.GeboortejaarTot != 0 && params.GeboortejaarVan > params.GeboortejaarTot {
+		fail(fmt.Errorf("geboortejaar_van must not be after geboortejaar_tot"))
+	}
+	if params.OverlijdensjaarVan != 0 && params.OverlijdensjaarTot != 0 && params.OverlijdensjaarVan > params.OverlijdensjaarTot {
+		fail(fmt.Errorf("overlijdensjaar_van must not be after overlijdensjaar_tot"))
+	}
+
+	dates := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"geboortedatum_van", &params.GeboortedatumVan},
+		{"geboortedatum_tot", &params.GeboortedatumTot},
+		{"overlijdensdatum_van", &params.OverlijdensdatumVan},
+		{"overlijdensdatum_tot", &params.OverlijdensdatumTot},
+	}
+	for _, d := range dates {
+		v := strings.TrimSpace(c.Query(d.name))
+		if v == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			fail(fmt.Errorf("%s must be a date in YYYY-MM-DD format", d.name))
+			continue
+		}
+		*d.dst = date
+	}
+	if !params.GeboortedatumVan.IsZero() && !params.GeboortedatumTot.IsZero() && params.GeboortedatumVan.After(params.GeboortedatumTot) {
+		fail(fmt.Errorf("geboortedatum_van must not be after geboortedatum_tot"))
+	}
+	if !params.OverlijdensdatumVan.IsZero() && !params.OverlijdensdatumTot.IsZero() && params.OverlijdensdatumVan.After(params.OverlijdensdatumTot) {
+		fail(fmt.Errorf("overlijdensdatum_van must not be after overlijdensdatum_tot"))
+	}
+
+	flags := []struct {
+		name string
+		dst  **bool
+	}{
+		{"has_photo", &params.HasPhoto},
+		{"has_scans", &params.HasScans},
+	}
+	for _, f := range flags {
+		v := c.Query(f.name)
+		if v == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(v)
+		if err != nil {
+			fail(fmt.Errorf("%s must be true or false", f.name))
+			continue
+		}
+		*f.dst = &value
+	}
+
+	return params, firstErr
+}
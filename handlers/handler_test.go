@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"bidprentjes-api/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	// Keep the index of the handler tests apart from the one of the store
+	// tests, which may run at the same time
+	dir, err := os.MkdirTemp("", "handlers-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	store.IndexDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestHandler returns a handler with an empty store, which is closed
+// when the test ends
+func newTestHandler(t *testing.T) (*Handler, *store.Store) {
+	t.Helper()
+	s := store.NewStore(context.Background(), "")
+	t.Cleanup(func() { s.Close() })
+	return NewHandler(s, "https://cdn.example.org", "https://example.org", DefaultMaxExport, ""), s
+}
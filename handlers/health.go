@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"bidprentjes-api/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusTimeout bounds how long the status endpoint waits for storage
+const statusTimeout = 5 * time.Second
+
+// Healthz reports that the process is alive, whatever the state of the index
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the service can answer searches: the index has
+// finished loading, holds restored, loaded or imported records and can be
+// read
+func (h *Handler) Readyz(c *gin.Context) {
+	if state, err := h.store.State(); state != store.StateReady {
+		reason := "the index is " + state
+		if err != nil {
+			reason = err.Error()
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": reason})
+		return
+	}
+	if !h.store.HasValidIndex() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": "no records have been loaded"})
+		return
+	}
+	if err := h.store.CheckIndex(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// AdminStatus reports the document count, where the index was loaded
+// from, the last backup, the mapping version and storage connectivity
+func (h *Handler) AdminStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), statusTimeout)
+	defer cancel()
+
+	c.JSON(http.StatusOK, h.store.Status(ctx))
+}
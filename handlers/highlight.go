@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"strings"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/translations"
+)
+
+// resultHighlight shows why a search result matched
+type resultHighlight struct {
+	Fields      map[string]template.HTML // Highlighted field values
+	Matched     []string                 // Labels of the matched fields
+	Score       float64
+	Explanation string // Indented score explanation, for admins
+}
+
+// resultHighlights returns the highlights of the search hits by ID
+func resultHighlights(hits []models.Hit, t translations.Translations) map[string]resultHighlight {
+	labels := map[string]string{
+		"id":                t.ID,
+		"voornaam":          t.FirstName,
+		"tussenvoegsel":     t.Prefix,
+		"achternaam":        t.LastName,
+		"geboorteplaats":    t.BirthPlace,
+		"overlijdensplaats": t.DeathPlace,
+		"scans":             t.Scans,
+	}
+
+	highlights := make(map[string]resultHighlight, len(hits))
+	for _, hit := range hits {
+		h := resultHighlight{
+			Fields: make(map[string]template.HTML, len(hit.Fragments)),
+			Score:  hit.Score,
+		}
+		// The fragments are escaped by the highlighter, only the
+		// <strong> tags around the matches are markup
+		for field, fragments := range hit.Fragments {
+			h.Fields[field] = template.HTML(strings.Join(fragments, " "))
+		}
+		for _, field := range hit.MatchedFields {
+			if label, ok := labels[field]; ok {
+				h.Matched = append(h.Matched, label)
+			}
+		}
+		if len(hit.Explanation) > 0 {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, hit.Explanation, "", "  "); err == nil {
+				h.Explanation = indented.String()
+			}
+		}
+		highlights[hit.ID] = h
+	}
+	return highlights
+}
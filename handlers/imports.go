@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/store"
+	"bidprentjes-api/translations"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxUploadSize limits the size of each uploaded CSV file
+	maxUploadSize = 256 << 20 // 256MB
+	// uploadTimeout is the time allowed to receive an upload
+	uploadTimeout = 10 * time.Minute
+	// importEventsHeartbeat is the interval of keep-alive comments on idle event streams
+	importEventsHeartbeat = 15 * time.Second
+)
+
+// readUploadedFile reads a multipart file completely, so it can be
+// processed after the request has finished
+func readUploadedFile(header *multipart.FileHeader) ([]byte, error) {
+	if header.Size > maxUploadSize {
+		return nil, fmt.Errorf("file %q is larger than %d bytes", header.Filename, maxUploadSize)
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %v", header.Filename, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %v", header.Filename, err)
+	}
+	if len(data) > maxUploadSize {
+		return nil, fmt.Errorf("file %q is larger than %d bytes", header.Filename, maxUploadSize)
+	}
+	return data, nil
+}
+
+// UploadForm renders the CSV upload page
+func (h *Handler) UploadForm(c *gin.Context) {
+	lang := adminLang(c)
+	t := translations.GetTranslation(lang)
+
+	c.HTML(http.StatusOK, "upload.html", gin.H{
+		"lang":      lang,
+		"languages": translations.SupportedLanguages,
+		"t":         t,
+		"title":     t.Upload,
+		"imports":   h.store.ListImports(),
+	})
+}
+
+// StartImport accepts a multipart upload with a bidprentjes CSV or A2A XML
+// file in the "file" field and an optional scans CSV in the "scans" field,
+// and starts an import job
+func (h *Handler) StartImport(c *gin.Context) {
+	// Uploads of large files take longer than the server's default read timeout
+	_ = http.NewResponseController(c.Writer).SetReadDeadline(time.Now().Add(uploadTimeout))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	data, err := readUploadedFile(fileHeader)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var scansData []byte
+	if scansHeader, err := c.FormFile("scans"); err == nil {
+		scansData, err = readUploadedFile(scansHeader)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	job, err := h.store.StartImport(context.WithoutCancel(c.Request.Context()), data, scansData)
+	if errors.Is(err, store.ErrImportRunning) {
+		apiError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "failed to start import")
+		return
+	}
+
+	state := job.Snapshot()
+	c.Header("Location", "/admin/imports/"+state.ID)
+	c.JSON(http.StatusAccepted, state)
+}
+
+// ListImports returns all known import jobs, newest first
+func (h *Handler) ListImports(c *gin.Context) {
+	c.JSON(http.StatusOK, h.store.ListImports())
+}
+
+// GetImport returns the state of a single import job
+func (h *Handler) GetImport(c *gin.Context) {
+	job, ok := h.store.GetImport(c.Param("id"))
+	if !ok {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("import %q not found", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, job.Snapshot())
+}
+
+// ImportEvents streams the progress of an import job as Server-Sent Events.
+// A "progress" event is sent on every change and a final "done" event when
+// the job has completed or failed.
+func (h *Handler) ImportEvents(c *gin.Context) {
+	job, ok := h.store.GetImport(c.Param("id"))
+	if !ok {
+		apiError(c, http.StatusNotFound, fmt.Sprintf("import %q not found", c.Param("id")))
+		return
+	}
+
+	// Event streams outlive the server's default write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(importEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	state, changed := job.Watch()
+	sent := false
+	c.Stream(func(w io.Writer) bool {
+		if !sent {
+			sent = true
+			return sendImportEvent(c, state)
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-changed:
+			state, changed = job.Watch()
+			return sendImportEvent(c, state)
+		}
+	})
+}
+
+// sendImportEvent writes the job state and reports whether the stream should continue
+func sendImportEvent(c *gin.Context, state models.ImportJob) bool {
+	if state.Status != models.ImportStatusRunning {
+		c.SSEvent("done", state)
+		return false
+	}
+	c.SSEvent("progress", state)
+	return true
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bidprentjes-api/store"
+	"bidprentjes-api/translations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadingRetryAfter is the number of seconds clients are asked to wait
+// before trying again while the index loads
+const loadingRetryAfter = 10
+
+// RequireReady answers requests with 503 Service Unavailable until the
+// store has loaded its index. Browsers get a page that reloads itself,
+// API clients a JSON error; both are told when to retry.
+func (h *Handler) RequireReady() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, _ := h.store.State()
+		if state == store.StateReady {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(loadingRetryAfter))
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") || !strings.Contains(c.GetHeader("Accept"), "text/html") {
+			message := "the archive is loading, try again later"
+			if state == store.StateFailed {
+				message = "the archive could not be loaded"
+			}
+			apiError(c, http.StatusServiceUnavailable, message)
+			return
+		}
+
+		lang := c.DefaultQuery("lang", "nl")
+		t := translations.GetTranslation(lang)
+		c.HTML(http.StatusServiceUnavailable, "loading.html", gin.H{
+			"lang":       lang,
+			"t":          t,
+			"failed":     state == store.StateFailed,
+			"retryAfter": loadingRetryAfter,
+		})
+		c.Abort()
+	}
+}
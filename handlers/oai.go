@@ -0,0 +1,655 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bidprentjes-api/a2a"
+	"bidprentjes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oaiNamespace      = "http://www.openarchives.org/OAI/2.0/"
+	oaiSchemaLocation = "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	oaiDCNamespace    = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	oaiDCSchema       = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	dcNamespace       = "http://purl.org/dc/elements/1.1/"
+	xsiNamespace      = "http://www.w3.org/2001/XMLSchema-instance"
+
+	oaiIdentifierNamespace = "http://www.openarchives.org/OAI/2.0/oai-identifier"
+	oaiIdentifierSchema    = "http://www.openarchives.org/OAI/2.0/oai-identifier.xsd"
+
+	// oaiDatestamp is the layout of datestamps at seconds granularity
+	oaiDatestamp = "2006-01-02T15:04:05Z"
+	oaiDay       = "2006-01-02"
+
+	// oaiPageSize is the number of records in one list response
+	oaiPageSize = 100
+)
+
+// OAI-PMH metadata prefixes
+const (
+	oaiPrefixDC  = "oai_dc"
+	oaiPrefixA2A = "a2a"
+)
+
+// OAI-PMH error codes
+const (
+	oaiBadArgument             = "badArgument"
+	oaiBadResumptionToken      = "badResumptionToken"
+	oaiBadVerb                 = "badVerb"
+	oaiCannotDisseminateFormat = "cannotDisseminateFormat"
+	oaiIDDoesNotExist          = "idDoesNotExist"
+	oaiNoRecordsMatch          = "noRecordsMatch"
+	oaiNoSetHierarchy          = "noSetHierarchy"
+)
+
+// oaiMetadataFormats are the formats every record is available in
+var oaiMetadataFormats = []oaiMetadataFormat{
+	{Prefix: oaiPrefixDC, Schema: oaiDCSchema, Namespace: oaiDCNamespace},
+	{Prefix: oaiPrefixA2A, Schema: a2a.Schema, Namespace: a2a.Namespace},
+}
+
+// oaiVerbArguments lists the arguments of every verb and whether they are
+// required. A resumption token is exclusive: no other argument may be given.
+var oaiVerbArguments = map[string]map[string]bool{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier": false},
+	"ListSets":            {"resumptionToken": false},
+	"ListIdentifiers":     {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	"ListRecords":         {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	"GetRecord":           {"identifier": true, "metadataPrefix": true},
+}
+
+type oaiResponse struct {
+	XMLName             xml.Name                `xml:"OAI-PMH"`
+	Xmlns               string                  `xml:"xmlns,attr"`
+	XmlnsXSI            string                  `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string                  `xml:"xsi:schemaLocation,attr"`
+	ResponseDate        string                  `xml:"responseDate"`
+	Request             oaiRequest              `xml:"request"`
+	Errors              []oaiError              `xml:"error"`
+	Identify            *oaiIdentify            `xml:"Identify"`
+	ListMetadataFormats *oaiListMetadataFormats `xml:"ListMetadataFormats"`
+	ListIdentifiers     *oaiListIdentifiers     `xml:"ListIdentifiers"`
+	ListRecords         *oaiListRecords         `xml:"ListRecords"`
+	GetRecord           *oaiGetRecord           `xml:"GetRecord"`
+}
+
+type oaiRequest struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	BaseURL         string `xml:",chardata"`
+}
+
+type oaiError struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+type oaiIdentify struct {
+	RepositoryName    string           `xml:"repositoryName"`
+	BaseURL           string           `xml:"baseURL"`
+	ProtocolVersion   string           `xml:"protocolVersion"`
+	AdminEmail        string           `xml:"adminEmail"`
+	EarliestDatestamp string           `xml:"earliestDatestamp"`
+	DeletedRecord     string           `xml:"deletedRecord"`
+	Granularity       string           `xml:"granularity"`
+	Description       []oaiDescription `xml:"description"`
+}
+
+type oaiDescription struct {
+	Identifier *oaiIdentifierDescription `xml:"oai-identifier"`
+	DC         *oaiDCDescription         `xml:"oai_dc:dc"`
+}
+
+// oaiDCDescription describes the repository in Dublin Core
+type oaiDCDescription struct {
+	XmlnsOAIDC     string `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+	Description    string `xml:"dc:description"`
+}
+
+type oaiIdentifierDescription struct {
+	Xmlns                string `xml:"xmlns,attr"`
+	XmlnsXSI             string `xml:"xmlns:xsi,attr"`
+	SchemaLocation       string `xml:"xsi:schemaLocation,attr"`
+	Scheme               string `xml:"scheme"`
+	RepositoryIdentifier string `xml:"repositoryIdentifier"`
+	Delimiter            string `xml:"delimiter"`
+	SampleIdentifier     string `xml:"sampleIdentifier"`
+}
+
+type oaiListMetadataFormats struct {
+	Formats []oaiMetadataFormat `xml:"metadataFormat"`
+}
+
+type oaiMetadataFormat struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+type oaiListIdentifiers struct {
+	Headers         []oaiHeader         `xml:"header"`
+	ResumptionToken *oaiResumptionToken `xml:"resumptionToken"`
+}
+
+type oaiListRecords struct {
+	Records         []oaiRecord         `xml:"record"`
+	ResumptionToken *oaiResumptionToken `xml:"resumptionToken"`
+}
+
+type oaiGetRecord struct {
+	Record oaiRecord `xml:"record"`
+}
+
+type oaiRecord struct {
+	Header   oaiHeader   `xml:"header"`
+	Metadata oaiMetadata `xml:"metadata"`
+}
+
+type oaiHeader struct {
+	Identifier string `xml:"identifier"`
+	Datestamp  string `xml:"datestamp"`
+}
+
+// oaiMetadata holds the record in exactly one of the metadata formats
+type oaiMetadata struct {
+	DC  *oaiDC        `xml:"oai_dc:dc"`
+	A2A *a2a.Document `xml:"A2A"`
+}
+
+type oaiResumptionToken struct {
+	CompleteListSize int    `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+	Token            string `xml:",chardata"`
+}
+
+// oaiDC is a bidprentje in unqualified Dublin Core
+type oaiDC struct {
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          string   `xml:"dc:title"`
+	Subject        string   `xml:"dc:subject,omitempty"`
+	Description    string   `xml:"dc:description,omitempty"`
+	Date           string   `xml:"dc:date,omitempty"`
+	Type           []string `xml:"dc:type"`
+	Format         string   `xml:"dc:format,omitempty"`
+	Identifier     string   `xml:"dc:identifier"`
+	Relation       []string `xml:"dc:relation"`
+	Coverage       []string `xml:"dc:coverage"`
+}
+
+// oaiToken is the state of a list request, carried in resumption tokens.
+// The datestamps are kept as given, with their granularity.
+type oaiToken struct {
+	MetadataPrefix string `json:"m"`
+	From           string `json:"f,omitempty"`
+	Until          string `json:"u,omitempty"`
+	After          string `json:"a"`
+	Cursor         int    `json:"c"`
+}
+
+// oaiRequestError is an OAI-PMH error condition
+type oaiRequestError struct {
+	code    string
+	message string
+}
+
+func (e *oaiRequestError) Error() string {
+	return e.code + ": " + e.message
+}
+
+// errOAIStoreFailed is returned when the records cannot be read. It is not
+// an OAI-PMH error condition and is answered with a server error, so
+// harvesters retry instead of concluding that nothing changed.
+var errOAIStoreFailed = &oaiRequestError{message: "the records could not be read"}
+
+func newOAIError(code, format string, args ...interface{}) *oaiRequestError {
+	return &oaiRequestError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// OAI is the OAI-PMH 2.0 endpoint for harvesting the collection. Records
+// are available as oai_dc and A2A and can be selected by last-modified
+// datestamp; deleted records are not tracked and there are no sets.
+func (h *Handler) OAI(c *gin.Context) {
+	baseURL := h.siteURL(c) + "/oai"
+	response := &oaiResponse{
+		Xmlns:          oaiNamespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: oaiSchemaLocation,
+		ResponseDate:   time.Now().UTC().Format(oaiDatestamp),
+		Request:        oaiRequest{BaseURL: baseURL},
+	}
+
+	if err := c.Request.ParseForm(); err != nil {
+		response.Errors = []oaiError{{Code: oaiBadArgument, Message: "the request arguments cannot be parsed"}}
+		h.writeOAI(c, response)
+		return
+	}
+
+	args, err := parseOAIArguments(c.Request.Form)
+	if err != nil {
+		response.Errors = []oaiError{{Code: err.code, Message: err.message}}
+		h.writeOAI(c, response)
+		return
+	}
+	// Valid arguments are echoed in the request element
+	response.Request = oaiRequest{
+		Verb:            args["verb"],
+		Identifier:      args["identifier"],
+		MetadataPrefix:  args["metadataPrefix"],
+		From:            args["from"],
+		Until:           args["until"],
+		Set:             args["set"],
+		ResumptionToken: args["resumptionToken"],
+		BaseURL:         baseURL,
+	}
+
+	switch args["verb"] {
+	case "Identify":
+		response.Identify = h.oaiIdentify(c, baseURL)
+	case "ListMetadataFormats":
+		response.ListMetadataFormats, err = h.oaiListMetadataFormats(c, args)
+	case "ListSets":
+		err = newOAIError(oaiNoSetHierarchy, "this repository does not support sets")
+		if args["resumptionToken"] != "" {
+			err = newOAIError(oaiBadResumptionToken, "the resumption token is invalid")
+		}
+	case "ListIdentifiers", "ListRecords":
+		err = h.oaiList(c, args, response)
+	case "GetRecord":
+		response.GetRecord, err = h.oaiGetRecord(c, args)
+	}
+	if err == errOAIStoreFailed {
+		c.String(http.StatusInternalServerError, "OAI-PMH request failed")
+		return
+	}
+	if err != nil {
+		response.Errors = []oaiError{{Code: err.code, Message: err.message}}
+	}
+	h.writeOAI(c, response)
+}
+
+// parseOAIArguments checks the request arguments against those of the
+// verb and returns them
+func parseOAIArguments(form url.Values) (map[string]string, *oaiRequestError) {
+	verbs := form["verb"]
+	if len(verbs) == 0 {
+		return nil, newOAIError(oaiBadVerb, "the verb argument is missing")
+	}
+	if len(verbs) > 1 {
+		return nil, newOAIError(oaiBadVerb, "the verb argument is repeated")
+	}
+	allowed, ok := oaiVerbArguments[verbs[0]]
+	if !ok {
+		return nil, newOAIError(oaiBadVerb, "%q is not an OAI-PMH verb", verbs[0])
+	}
+
+	args := map[string]string{"verb": verbs[0]}
+	for name, values := range form {
+		if name == "verb" {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return nil, newOAIError(oaiBadArgument, "%s does not take the argument %q", verbs[0], name)
+		}
+		if len(values) > 1 {
+			return nil, newOAIError(oaiBadArgument, "the argument %q is repeated", name)
+		}
+		args[name] = values[0]
+	}
+
+	if _, ok := args["resumptionToken"]; ok {
+		if len(args) > 2 {
+			return nil, newOAIError(oaiBadArgument, "resumptionToken is an exclusive argument")
+		}
+		return args, nil
+	}
+	for name, required := range allowed {
+		if _, ok := args[name]; required && !ok {
+			return nil, newOAIError(oaiBadArgument, "%s requires the argument %q", verbs[0], name)
+		}
+	}
+	return args, nil
+}
+
+// writeOAI writes the response document
+func (h *Handler) writeOAI(c *gin.Context, response *oaiResponse) {
+	output, err := xml.MarshalIndent(response, "", "  ")
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "OAI-PMH response failed", "error", err)
+		c.String(http.StatusInternalServerError, "OAI-PMH response failed")
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", append([]byte(xml.Header), output...))
+}
+
+// oaiDeletedRecordsNote explains in the Identify response why deletedRecord
+// is "no"
+const oaiDeletedRecordsNote = "Deleted records are not tracked: a record that is removed from the collection " +
+	"disappears from the repository without a deleted header, also for harvests with from. " +
+	"Harvest the complete list from time to time to remove records that no longer exist."
+
+func (h *Handler) oaiIdentify(c *gin.Context, baseURL string) *oaiIdentify {
+	earliest := h.store.EarliestModified()
+	if earliest.IsZero() {
+		earliest = time.Now()
+	}
+	repository := h.oaiRepositoryIdentifier(c)
+
+	adminEmail := h.adminEmail
+	if adminEmail == "" {
+		adminEmail = "webmaster@" + repository
+	}
+
+	return &oaiIdentify{
+		RepositoryName:    "Bidprentjes",
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        adminEmail,
+		EarliestDatestamp: earliest.UTC().Format(oaiDatestamp),
+		DeletedRecord:     "no",
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+		Description: []oaiDescription{
+			{Identifier: &oaiIdentifierDescription{
+				Xmlns:                oaiIdentifierNamespace,
+				XmlnsXSI:             xsiNamespace,
+				SchemaLocation:       oaiIdentifierNamespace + " " + oaiIdentifierSchema,
+				Scheme:               "oai",
+				RepositoryIdentifier: repository,
+				Delimiter:            ":",
+				SampleIdentifier:     h.oaiIdentifier(c, "1"),
+			}},
+			{DC: &oaiDCDescription{
+				XmlnsOAIDC:     oaiDCNamespace,
+				XmlnsDC:        dcNamespace,
+				XmlnsXSI:       xsiNamespace,
+				SchemaLocation: oaiDCNamespace + " " + oaiDCSchema,
+				Description:    oaiDeletedRecordsNote,
+			}},
+		},
+	}
+}
+
+func (h *Handler) oaiListMetadataFormats(c *gin.Context, args map[string]string) (*oaiListMetadataFormats, *oaiRequestError) {
+	if identifier, ok := args["identifier"]; ok {
+		if _, err := h.oaiLookup(c, identifier); err != nil {
+			return nil, err
+		}
+	}
+	return &oaiListMetadataFormats{Formats: oaiMetadataFormats}, nil
+}
+
+func (h *Handler) oaiGetRecord(c *gin.Context, args map[string]string) (*oaiGetRecord, *oaiRequestError) {
+	if err := checkOAIMetadataPrefix(args["metadataPrefix"]); err != nil {
+		return nil, err
+	}
+	b, err := h.oaiLookup(c, args["identifier"])
+	if err != nil {
+		return nil, err
+	}
+	return &oaiGetRecord{Record: h.oaiRecord(c, b, args["metadataPrefix"])}, nil
+}
+
+// oaiList answers ListIdentifiers and ListRecords with a page of records,
+// followed by a resumption token when more records match
+func (h *Handler) oaiList(c *gin.Context, args map[string]string, response *oaiResponse) *oaiRequestError {
+	token := oaiToken{
+		MetadataPrefix: args["metadataPrefix"],
+		From:           args["from"],
+		Until:          args["until"],
+	}
+	resumed := false
+	if value, ok := args["resumptionToken"]; ok {
+		var err error
+		if token, err = decodeOAIToken(value); err != nil {
+			return newOAIError(oaiBadResumptionToken, "the resumption token is invalid")
+		}
+		resumed = true
+	}
+
+	if _, ok := args["set"]; ok {
+		return newOAIError(oaiNoSetHierarchy, "this repository does not support sets")
+	}
+	if err := checkOAIMetadataPrefix(token.MetadataPrefix); err != nil {
+		return err
+	}
+	from, until, err := parseOAIRange(token.From, token.Until)
+	if err != nil {
+		if resumed {
+			return newOAIError(oaiBadResumptionToken, "the resumption token is invalid")
+		}
+		return err
+	}
+
+	page, storeErr := h.store.Harvest(from, until, token.After, oaiPageSize)
+	if storeErr != nil {
+		slog.ErrorContext(c.Request.Context(), "OAI-PMH harvest failed", "error", storeErr)
+		return errOAIStoreFailed
+	}
+	if len(page.Items) == 0 {
+		if resumed {
+			return newOAIError(oaiBadResumptionToken, "the resumption token has expired")
+		}
+		return newOAIError(oaiNoRecordsMatch, "no records match the request")
+	}
+
+	// The last page of a resumed list has an empty token, a complete list
+	// on a single page has none
+	var resumption *oaiResumptionToken
+	if page.Last != "" || resumed {
+		resumption = &oaiResumptionToken{CompleteListSize: page.Total, Cursor: token.Cursor}
+	}
+	if page.Last != "" {
+		next := token
+		next.After = page.Last
+		next.Cursor += len(page.Items)
+		resumption.Token = encodeOAIToken(next)
+	}
+
+	if args["verb"] == "ListIdentifiers" {
+		list := &oaiListIdentifiers{ResumptionToken: resumption}
+		for i := range page.Items {
+			list.Headers = append(list.Headers, h.oaiHeader(c, &page.Items[i]))
+		}
+		response.ListIdentifiers = list
+		return nil
+	}
+	list := &oaiListRecords{ResumptionToken: resumption}
+	for i := range page.Items {
+		list.Records = append(list.Records, h.oaiRecord(c, &page.Items[i], token.MetadataPrefix))
+	}
+	response.ListRecords = list
+	return nil
+}
+
+// oaiLookup returns the bidprentje of an OAI identifier
+func (h *Handler) oaiLookup(c *gin.Context, identifier string) (*models.Bidprentje, *oaiRequestError) {
+	prefix := "oai:" + h.oaiRepositoryIdentifier(c) + ":"
+	if strings.HasPrefix(identifier, prefix) {
+		if b, exists := h.store.Get(strings.TrimPrefix(identifier, prefix)); exists {
+			return b, nil
+		}
+	}
+	return nil, newOAIError(oaiIDDoesNotExist, "%q is not a known identifier", identifier)
+}
+
+// oaiRepositoryIdentifier returns the domain name of the site, which
+// identifies the repository in OAI identifiers
+func (h *Handler) oaiRepositoryIdentifier(c *gin.Context) string {
+	if u, err := url.Parse(h.siteURL(c)); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "localhost"
+}
+
+// oaiIdentifier returns the OAI identifier of the bidprentje with id,
+// e.g. oai:bidprentjes.example.org:1234
+func (h *Handler) oaiIdentifier(c *gin.Context, id string) string {
+	return "oai:" + h.oaiRepositoryIdentifier(c) + ":" + id
+}
+
+func (h *Handler) oaiHeader(c *gin.Context, b *models.Bidprentje) oaiHeader {
+	return oaiHeader{
+		Identifier: h.oaiIdentifier(c, b.ID),
+		Datestamp:  b.LastModified.UTC().Format(oaiDatestamp),
+	}
+}
+
+func (h *Handler) oaiRecord(c *gin.Context, b *models.Bidprentje, metadataPrefix string) oaiRecord {
+	permalink := h.siteURL(c) + h.permalinkPath(b.ID)
+	record := oaiRecord{Header: h.oaiHeader(c, b)}
+	if metadataPrefix == oaiPrefixA2A {
+		record.Metadata.A2A = a2a.FromBidprentje(b, a2a.Options{
+			Permalink: func(string) string { return permalink },
+			ScanURLs:  h.scanURLs,
+		})
+		return record
+	}
+	record.Metadata.DC = h.oaiDC(b, permalink)
+	return record
+}
+
+// oaiDC describes a bidprentje in Dublin Core: the deceased is the
+// subject, the death date the date and the places the coverage
+func (h *Handler) oaiDC(b *models.Bidprentje, permalink string) *oaiDC {
+	name := displayName(b)
+	dc := &oaiDC{
+		XmlnsOAIDC:     oaiDCNamespace,
+		XmlnsDC:        dcNamespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: oaiDCNamespace + " " + oaiDCSchema,
+		Title:          strings.TrimSpace("Bidprentje " + name),
+		Subject:        name,
+		Date:           exportDate(b.Overlijdensdatum),
+		Type:           []string{"Text"},
+		Identifier:     permalink,
+		Relation:       h.scanURLs(b),
+	}
+
+	var description []string
+	if event := describeOAIEvent("Geboren", b.Geboortedatum, b.Geboorteplaats); event != "" {
+		description = append(description, event)
+	}
+	if event := describeOAIEvent("Overleden", b.Overlijdensdatum, b.Overlijdensplaats); event != "" {
+		description = append(description, event)
+	}
+	dc.Description = strings.Join(description, "; ")
+
+	for _, place := range []string{b.Geboorteplaats, b.Overlijdensplaats} {
+		place = strings.TrimSpace(place)
+		if place != "" && (len(dc.Coverage) == 0 || dc.Coverage[0] != place) {
+			dc.Coverage = append(dc.Coverage, place)
+		}
+	}
+	if len(b.Scans) > 0 {
+		dc.Type = append(dc.Type, "Image")
+		dc.Format = "image/jpeg"
+	}
+	return dc
+}
+
+// describeOAIEvent describes a birth or death, e.g. "Geboren 1860-03-01
+// te Venlo", empty when neither the date nor the place is known
+func describeOAIEvent(label string, date time.Time, place string) string {
+	place = strings.TrimSpace(place)
+	if date.IsZero() && place == "" {
+		return ""
+	}
+	description := label
+	if !date.IsZero() {
+		description += " " + exportDate(date)
+	}
+	if place != "" {
+		description += " te " + place
+	}
+	return description
+}
+
+// checkOAIMetadataPrefix checks that records are available in the format
+func checkOAIMetadataPrefix(prefix string) *oaiRequestError {
+	for _, format := range oaiMetadataFormats {
+		if format.Prefix == prefix {
+			return nil
+		}
+	}
+	return newOAIError(oaiCannotDisseminateFormat, "the metadata format %q is not supported", prefix)
+}
+
+// parseOAIRange parses the from and until arguments, which must have the
+// same granularity. A day includes all of its seconds.
+func parseOAIRange(fromValue, untilValue string) (time.Time, time.Time, *oaiRequestError) {
+	from, fromDay, err := parseOAIDatestamp(fromValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, newOAIError(oaiBadArgument, "from %q is not a valid datestamp", fromValue)
+	}
+	until, untilDay, err := parseOAIDatestamp(untilValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, newOAIError(oaiBadArgument, "until %q is not a valid datestamp", untilValue)
+	}
+	if fromValue != "" && untilValue != "" {
+		if fromDay != untilDay {
+			return time.Time{}, time.Time{}, newOAIError(oaiBadArgument, "from and until have a different granularity")
+		}
+		if until.Before(from) {
+			return time.Time{}, time.Time{}, newOAIError(oaiBadArgument, "from is later than until")
+		}
+	}
+	if untilDay && !until.IsZero() {
+		until = until.Add(24*time.Hour - time.Second)
+	}
+	return from, until, nil
+}
+
+// parseOAIDatestamp parses a datestamp as a day or at seconds granularity
+// and reports whether it was a day. An empty value is the zero time.
+func parseOAIDatestamp(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.Parse(oaiDay, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(oaiDatestamp, value)
+	return t, false, err
+}
+
+func encodeOAIToken(token oaiToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOAIToken(value string) (oaiToken, error) {
+	var token oaiToken
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return token, err
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, err
+	}
+	if token.After == "" || token.Cursor < 0 {
+		return token, fmt.Errorf("incomplete resumption token")
+	}
+	return token, nil
+}
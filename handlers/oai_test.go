@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oaiTestResponse holds the parts of an OAI-PMH response the tests check
+type oaiTestResponse struct {
+	Errors []struct {
+		Code string `xml:"code,attr"`
+	} `xml:"error"`
+	Identify *struct {
+		DeletedRecord string `xml:"deletedRecord"`
+		Descriptions  []struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"description"`
+	} `xml:"Identify"`
+	ListMetadataFormats *struct {
+		Prefixes []string `xml:"metadataFormat>metadataPrefix"`
+	} `xml:"ListMetadataFormats"`
+	ListIdentifiers *struct {
+		Identifiers     []string          `xml:"header>identifier"`
+		ResumptionToken oaiTestResumption `xml:"resumptionToken"`
+	} `xml:"ListIdentifiers"`
+	ListRecords *struct {
+		Records []struct {
+			Identifier string `xml:"header>identifier"`
+			Metadata   string `xml:",innerxml"`
+		} `xml:"record"`
+		ResumptionToken oaiTestResumption `xml:"resumptionToken"`
+	} `xml:"ListRecords"`
+	GetRecord *struct {
+		Identifier string `xml:"record>header>identifier"`
+		Metadata   string `xml:",innerxml"`
+	} `xml:"GetRecord"`
+}
+
+type oaiTestResumption struct {
+	CompleteListSize int    `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+	Token            string `xml:",chardata"`
+}
+
+func TestOAI(t *testing.T) {
+	h, s := newTestHandler(t)
+
+	// More records than fit in one list response
+	var bidprentjes []*models.Bidprentje
+	for i := 1; i <= oaiPageSize+5; i++ {
+		bidprentjes = append(bidprentjes, &models.Bidprentje{ID: fmt.Sprintf("%03d", i), Voornaam: "Jan", Achternaam: "Jansen", Overlijdensplaats: "Venlo"})
+	}
+	if err := s.BatchCreate(bidprentjes); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/oai", h.OAI)
+	r.POST("/oai", h.OAI)
+	request := func(query string) (int, oaiTestResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oai?"+query, nil))
+		var response oaiTestResponse
+		if w.Code == http.StatusOK {
+			if err := xml.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+		}
+		return w.Code, response
+	}
+	errorCode := func(query string) string {
+		t.Helper()
+		status, response := request(query)
+		if status != http.StatusOK || len(response.Errors) != 1 {
+			t.Errorf("%s: expected one OAI-PMH error, got status %d and %+v", query, status, response.Errors)
+			return ""
+		}
+		return response.Errors[0].Code
+	}
+
+	t.Run("Identify", func(t *testing.T) {
+		_, response := request("verb=Identify")
+		if response.Identify == nil || response.Identify.DeletedRecord != "no" {
+			t.Fatalf("Expected an Identify response without deleted records, got %+v", response.Identify)
+		}
+		noted := false
+		for _, description := range response.Identify.Descriptions {
+			noted = noted || strings.Contains(description.Inner, "Deleted records are not tracked")
+		}
+		if !noted {
+			t.Error("Expected the description to explain that deletions are not tracked")
+		}
+	})
+
+	t.Run("ListMetadataFormats", func(t *testing.T) {
+		_, response := request("verb=ListMetadataFormats&identifier=oai:example.org:001")
+		if response.ListMetadataFormats == nil || fmt.Sprint(response.ListMetadataFormats.Prefixes) != "[oai_dc a2a]" {
+			t.Errorf("Expected oai_dc and a2a, got %+v", response.ListMetadataFormats)
+		}
+	})
+
+	t.Run("ListIdentifiers", func(t *testing.T) {
+		_, first := request("verb=ListIdentifiers&metadataPrefix=oai_dc")
+		if first.ListIdentifiers == nil {
+			t.Fatalf("Expected identifiers, got %+v", first.Errors)
+		}
+		list := first.ListIdentifiers
+		if len(list.Identifiers) != oaiPageSize || list.Identifiers[0] != "oai:example.org:001" {
+			t.Errorf("Expected %d identifiers from oai:example.org:001, got %d", oaiPageSize, len(list.Identifiers))
+		}
+		if list.ResumptionToken.Token == "" || list.ResumptionToken.CompleteListSize != oaiPageSize+5 {
+			t.Fatalf("Expected a resumption token for %d records, got %+v", oaiPageSize+5, list.ResumptionToken)
+		}
+
+		_, next := request("verb=ListIdentifiers&resumptionToken=" + url.QueryEscape(list.ResumptionToken.Token))
+		if next.ListIdentifiers == nil {
+			t.Fatalf("Expected the next identifiers, got %+v", next.Errors)
+		}
+		list = next.ListIdentifiers
+		if len(list.Identifiers) != 5 || list.Identifiers[0] != fmt.Sprintf("oai:example.org:%03d", oaiPageSize+1) {
+			t.Errorf("Expected the last 5 identifiers, got %v", list.Identifiers)
+		}
+		if list.ResumptionToken.Token != "" || list.ResumptionToken.Cursor != oaiPageSize {
+			t.Errorf("Expected an empty last token at cursor %d, got %+v", oaiPageSize, list.ResumptionToken)
+		}
+	})
+
+	t.Run("ListRecords", func(t *testing.T) {
+		_, response := request("verb=ListRecords&metadataPrefix=oai_dc&from=2000-01-01")
+		if response.ListRecords == nil || len(response.ListRecords.Records) != oaiPageSize {
+			t.Fatalf("Expected a page of records, got %+v", response.Errors)
+		}
+		if metadata := response.ListRecords.Records[0].Metadata; !strings.Contains(metadata, "Jan Jansen") {
+			t.Errorf("Expected Dublin Core metadata with the name, got %s", metadata)
+		}
+	})
+
+	t.Run("GetRecord", func(t *testing.T) {
+		_, response := request("verb=GetRecord&metadataPrefix=a2a&identifier=oai:example.org:002")
+		if response.GetRecord == nil || response.GetRecord.Identifier != "oai:example.org:002" {
+			t.Fatalf("Expected record 002, got %+v", response.Errors)
+		}
+		if !strings.Contains(response.GetRecord.Metadata, "<A2A") || !strings.Contains(response.GetRecord.Metadata, "Venlo") {
+			t.Errorf("Expected A2A metadata, got %s", response.GetRecord.Metadata)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			query string
+			code  string
+		}{
+			{"", oaiBadVerb},
+			{"verb=Harvest", oaiBadVerb},
+			{"verb=ListRecords", oaiBadArgument},
+			{"verb=ListRecords&metadataPrefix=oai_dc&from=yesterday", oaiBadArgument},
+			{"verb=ListRecords&metadataPrefix=oai_dc&from=2000-01-01&until=1999-01-01T00:00:00Z", oaiBadArgument},
+			{"verb=Identify&metadataPrefix=oai_dc", oaiBadArgument},
+			{"verb=GetRecord&identifier=oai:example.org:001", oaiBadArgument},
+			{"verb=ListIdentifiers&resumptionToken=garbage", oaiBadResumptionToken},
+			{"verb=ListIdentifiers&resumptionToken=garbage&metadataPrefix=oai_dc", oaiBadArgument},
+			{"verb=ListRecords&metadataPrefix=marc21", oaiCannotDisseminateFormat},
+			{"verb=GetRecord&metadataPrefix=marc21&identifier=oai:example.org:001", oaiCannotDisseminateFormat},
+			{"verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:example.org:999", oaiIDDoesNotExist},
+			{"verb=ListRecords&metadataPrefix=oai_dc&from=2999-01-01", oaiNoRecordsMatch},
+			{"verb=ListSets", oaiNoSetHierarchy},
+		}
+		for _, tt := range tests {
+			if code := errorCode(tt.query); code != tt.code {
+				t.Errorf("%q: expected %s, got %s", tt.query, tt.code, code)
+			}
+		}
+	})
+
+}
+
+func TestOAIStoreFailure(t *testing.T) {
+	// A closed store cannot be read
+	s := store.NewStore(context.Background(), "")
+	s.Close()
+	h := NewHandler(s, "", "https://example.org", DefaultMaxExport, "")
+
+	r := gin.New()
+	r.GET("/oai", h.OAI)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oai?verb=ListIdentifiers&metadataPrefix=oai_dc", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 when the records cannot be read, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+	"bidprentjes-api/translations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordScan is a scan in the viewer of the record page
+type recordScan struct {
+	URL   string
+	Label string
+}
+
+// WebRecord renders the page of a single bidprentje, which is its permalink
+func (h *Handler) WebRecord(c *gin.Context) {
+	lang := c.DefaultQuery("lang", "nl")
+	t := translations.GetTranslation(lang)
+
+	b, exists := h.store.Get(c.Param("id"))
+	if !exists {
+		c.HTML(http.StatusNotFound, "record.html", gin.H{
+			"lang":      lang,
+			"languages": translations.SupportedLanguages,
+			"t":         t,
+			"title":     t.NotFound,
+		})
+		return
+	}
+
+	permalink := h.siteURL(c) + h.permalinkPath(b.ID)
+	name := displayName(b)
+
+	c.HTML(http.StatusOK, "record.html", gin.H{
+		"b":         b,
+		"lang":      lang,
+		"languages": translations.SupportedLanguages,
+		"t":         t,
+		"title":     strings.TrimSpace("Bidprentje " + name),
+		"permalink": permalink,
+		"citation":  recordCitation(b, permalink, t, time.Now()),
+		"scans":     h.recordScans(b, t),
+		"jsonLD":    h.recordJSONLD(b, permalink),
+	})
+}
+
+// recordScans lists the scans of b for the viewer. The first two scans are
+// the front and the back of the card.
+func (h *Handler) recordScans(b *models.Bidprentje, t translations.Translations) []recordScan {
+	scans := make([]recordScan, 0, len(b.Scans))
+	for i, scan := range b.Scans {
+		label := fmt.Sprintf("%s %d", t.Scan, i+1)
+		switch i {
+		case 0:
+			label = t.Front
+		case 1:
+			label = t.Reverse
+		}
+		scans = append(scans, recordScan{URL: h.scanURL(scan), Label: label})
+	}
+	return scans
+}
+
+// displayName returns the full name of the deceased, e.g. "Jan van den Berg"
+func displayName(b *models.Bidprentje) string {
+	return strings.Join(strings.Fields(b.Voornaam+" "+b.Tussenvoegsel+" "+b.Achternaam), " ")
+}
+
+// lifespan returns the birth and death years of b, e.g. "1860–1918" or
+// "† 1918", empty when neither is known
+func lifespan(b *models.Bidprentje) string {
+	switch {
+	case !b.Geboortedatum.IsZero() && !b.Overlijdensdatum.IsZero():
+		return fmt.Sprintf("%d–%d", b.Geboortedatum.Year(), b.Overlijdensdatum.Year())
+	case !b.Overlijdensdatum.IsZero():
+		return fmt.Sprintf("† %d", b.Overlijdensdatum.Year())
+	case !b.Geboortedatum.IsZero():
+		return fmt.Sprintf("* %d", b.Geboortedatum.Year())
+	}
+	return ""
+}
+
+// recordCitation returns a citation of the bidprentje for use in
+// genealogies, e.g. "Bidprentje Jan Jansen (1860–1918), Bidprentjes, ID 12,
+// https://bidprentjes.example.org/bidprentje/12 (accessed 2024-05-01)."
+func recordCitation(b *models.Bidprentje, permalink string, t translations.Translations, accessed time.Time) string {
+	citation := strings.TrimSpace("Bidprentje " + displayName(b))
+	if span := lifespan(b); span != "" {
+		citation += " (" + span + ")"
+	}
+	return fmt.Sprintf("%s, Bidprentjes, %s %s, %s (%s %s).",
+		citation, t.ID, b.ID, permalink, t.Accessed, accessed.Format("2006-01-02"))
+}
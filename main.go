@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"bidprentjes-api/handlers"
+	"bidprentjes-api/logging"
+	"bidprentjes-api/metrics"
 	"bidprentjes-api/store"
 
 	"github.com/gin-gonic/gin"
@@ -21,31 +24,89 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Get bucket name from environment variable
-	bucketName := os.Getenv("STORAGE_BUCKET")
-	if bucketName == "" {
-		log.Printf("Warning: STORAGE_BUCKET environment variable not set, running in local-only mode")
+	// Structured JSON logging at LOG_LEVEL (debug, info, warn or error)
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(logging.New(os.Stderr, level))
+	if err != nil {
+		slog.Error("LOG_LEVEL must be debug, info, warn or error", "value", os.Getenv("LOG_LEVEL"))
+		os.Exit(1)
+	}
+
+	// Get storage backend from environment variables, STORAGE_BUCKET is kept
+	// as a shorthand for a GCS bucket
+	storageURL := os.Getenv("STORAGE_URL")
+	if storageURL == "" {
+		if bucketName := os.Getenv("STORAGE_BUCKET"); bucketName != "" {
+			storageURL = "gs://" + bucketName
+		}
+	}
+	if storageURL == "" {
+		slog.Warn("STORAGE_URL environment variable not set, running in local-only mode")
+	}
+
+	// Directory of the search index
+	if indexDir := os.Getenv("INDEX_DIR"); indexDir != "" {
+		store.IndexDir = indexDir
 	}
 
 	cdnBaseURL := os.Getenv("CDN_BASE_URL")
 	if cdnBaseURL == "" {
-		log.Printf("Warning: CDN_BASE_URL environment variable not set")
+		slog.Warn("CDN_BASE_URL environment variable not set")
 	}
 
+	// Public URL of the site for permalinks, taken from the request when
+	// not set
+	publicURL := os.Getenv("PUBLIC_URL")
+
+	adminUsername := os.Getenv("ADMIN_USERNAME")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	if adminPassword == "" {
+		slog.Warn("ADMIN_PASSWORD environment variable not set, admin endpoints are disabled")
+	}
+
+	// First-name variants, the built-in list is used when not set
+	synonymsFile := os.Getenv("FIRST_NAME_SYNONYMS_FILE")
+
+	// Maximum number of records in one export
+	maxExport := handlers.DefaultMaxExport
+	if v := os.Getenv("MAX_EXPORT_RECORDS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			slog.Error("MAX_EXPORT_RECORDS must be a positive number", "value", v)
+			os.Exit(1)
+		}
+		maxExport = n
+	}
+
+	// Contact address in the OAI-PMH Identify response
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Initialize store with bucket name
-	store := store.NewStore(ctx, bucketName)
+	// Initialize store with storage backend, the index loads in the
+	// background while the server already answers
+	store := store.StartStore(ctx, storageURL)
 	defer store.Close()
 
+	if _, err := store.LoadSynonyms(synonymsFile); err != nil {
+		slog.Warn("Failed to load first-name synonyms, using the built-in list", "path", synonymsFile, "error", err)
+	}
+
 	// Initialize handlers with store
-	handler := handlers.NewHandler(store, cdnBaseURL)
+	handler := handlers.NewHandler(store, cdnBaseURL, publicURL, maxExport, adminEmail)
 
-	// Create Gin router
-	r := gin.Default()
+	// Create Gin router, which logs every request with its request ID and
+	// logs health probes and metrics scrapes only at debug level
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(logging.Middleware("/healthz", "/readyz", "/metrics"))
+	r.Use(metrics.Middleware())
 
 	// Add template functions
 	r.SetFuncMap(template.FuncMap{
@@ -71,12 +132,80 @@ func main() {
 	})
 
 	// Load HTML templates
-	log.Println("Loading templates from templates/*")
 	r.LoadHTMLGlob("templates/*.html")
-	log.Println("Templates loaded successfully")
+	slog.Info("Templates loaded", "path", "templates/*.html")
+
+	// Liveness and readiness probes
+	r.GET("/healthz", handler.Healthz)
+	r.GET("/readyz", handler.Readyz)
+
+	// Prometheus metrics, the document count only once the index has loaded
+	metrics.RegisterDocuments(func() float64 {
+		if !store.Ready() {
+			return 0
+		}
+		return float64(store.Count())
+	})
+	r.GET("/metrics", metrics.Handler())
+
+	// Routes using the index answer 503 until it has loaded
+	ready := handler.RequireReady()
 
 	// Keep only search and upload web endpoints
-	r.GET("/search", handler.WebSearch)
+	r.GET("/search", ready, handler.WebSearch)
+	r.GET("/bidprentje/:id", ready, handler.WebRecord)
+
+	// Discoverability for search engines and browsers
+	r.GET("/sitemap.xml", ready, handler.Sitemap)
+	r.GET("/sitemap/:page", ready, handler.SitemapPage)
+	r.GET("/robots.txt", handler.Robots)
+	r.GET("/opensearch.xml", handler.OpenSearch)
+
+	// OAI-PMH provider for harvesting, which allows both GET and POST
+	r.GET("/oai", ready, handler.OAI)
+	r.POST("/oai", ready, handler.OAI)
+
+	adminAuth := handlers.AdminAuth(adminUsername, adminPassword)
+	sameOrigin := handler.SameOrigin()
+
+	// Versioned JSON API
+	api := r.Group("/api/v1", ready)
+	{
+		api.GET("/bidprentjes", handler.APIList)
+		api.GET("/bidprentjes/:id", handler.APIGet)
+		api.GET("/bidprentjes/:id/export", handler.ExportBidprentje)
+		api.GET("/search", handlers.ExplainAuth(adminAuth), handler.APISearch)
+		api.GET("/suggest", handler.APISuggest)
+		api.GET("/export", handler.Export)
+
+		api.POST("/bidprentjes", sameOrigin, adminAuth, handler.APICreate)
+		api.PUT("/bidprentjes/:id", sameOrigin, adminAuth, handler.APIUpdate)
+		api.DELETE("/bidprentjes/:id", sameOrigin, adminAuth, handler.APIDelete)
+	}
+
+	// The status is also available while the index loads
+	r.GET("/admin/status", adminAuth, handler.AdminStatus)
+
+	// Admin web endpoints, whose form posts must come from this site
+	admin := r.Group("/admin", sameOrigin, adminAuth, ready)
+	{
+		admin.GET("/search", handler.AdminSearch)
+		admin.GET("/bidprentjes/new", handler.AdminNewForm)
+		admin.POST("/bidprentjes", handler.AdminCreate)
+		admin.GET("/bidprentjes/:id/edit", handler.AdminEditForm)
+		admin.POST("/bidprentjes/:id", handler.AdminUpdate)
+		admin.POST("/bidprentjes/:id/delete", handler.AdminDelete)
+
+		admin.GET("/upload", handler.UploadForm)
+		admin.POST("/imports", handler.StartImport)
+		admin.GET("/imports", handler.ListImports)
+		admin.GET("/imports/:id", handler.GetImport)
+		admin.GET("/imports/:id/events", handler.ImportEvents)
+
+		admin.POST("/synonyms/reload", handler.ReloadSynonyms)
+		admin.POST("/reindex", handler.Reindex)
+		admin.GET("/reindex", handler.ReindexStatus)
+	}
 
 	// Create a server with timeouts
 	srv := &http.Server{
@@ -90,9 +219,10 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting server on %s", srv.Addr)
+		slog.Info("Starting server", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -101,7 +231,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Create a timeout context for shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -109,11 +239,11 @@ func main() {
 
 	// Shutdown the server
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
 	}
 
 	// Cancel the main context to trigger cleanup in other goroutines
 	cancel()
 
-	log.Println("Server exiting")
+	slog.Info("Server exiting")
 }
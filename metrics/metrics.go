@@ -0,0 +1,152 @@
+// Package metrics holds the Prometheus metrics of the service and the
+// middleware and handler that expose them.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bidprentjes"
+
+// Backup operations, the values of the operation label
+const (
+	OperationBackup  = "backup"
+	OperationRestore = "restore"
+)
+
+var (
+	searchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_duration_seconds",
+		Help:      "Time taken by the index to answer a search, by match mode.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"mode"})
+
+	searchHits = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_hits",
+		Help:      "Number of records matching a search, by match mode.",
+		Buckets:   []float64{0, 1, 5, 10, 50, 100, 500, 1000, 10000},
+	}, []string{"mode"})
+
+	// Divided by the count of search_hits this gives the zero-result rate
+	searchZeroResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "search_zero_results_total",
+		Help:      "Number of searches without any match, by match mode.",
+	}, []string{"mode"})
+
+	importDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "import_duration_seconds",
+		Help:      "Time taken to import a file of records.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	})
+
+	importRecords = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "import_records_total",
+		Help:      "Number of records imported.",
+	})
+
+	importRecordsPerSecond = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "import_records_per_second",
+		Help:      "Records per second stored by the last import.",
+	})
+
+	backupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backup_duration_seconds",
+		Help:      "Time taken to back up or restore the index, by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"operation"})
+
+	backupSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_size_bytes",
+		Help:      "Size of the last index archive backed up or restored, by operation.",
+	}, []string{"operation"})
+
+	backupFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backup_failures_total",
+		Help:      "Number of failed backups and restores of the index, by operation.",
+	}, []string{"operation"})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to answer HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// RegisterDocuments reports the number of documents in the store, read
+// from count whenever the metrics are collected
+func RegisterDocuments(count func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "documents",
+		Help:      "Number of bidprentjes in the store.",
+	}, count)
+}
+
+// ObserveSearch records the duration and number of hits of a search
+func ObserveSearch(mode string, duration time.Duration, hits uint64) {
+	searchDuration.WithLabelValues(mode).Observe(duration.Seconds())
+	searchHits.WithLabelValues(mode).Observe(float64(hits))
+	if hits == 0 {
+		searchZeroResults.WithLabelValues(mode).Inc()
+	}
+}
+
+// ObserveImport records the duration and throughput of an import
+func ObserveImport(duration time.Duration, records int) {
+	importDuration.Observe(duration.Seconds())
+	importRecords.Add(float64(records))
+	if seconds := duration.Seconds(); seconds > 0 {
+		importRecordsPerSecond.Set(float64(records) / seconds)
+	}
+}
+
+// ObserveBackup records the duration and archive size of a backup or
+// restore, or its failure when err is not nil
+func ObserveBackup(operation string, duration time.Duration, size int64, err error) {
+	if err != nil {
+		backupFailures.WithLabelValues(operation).Inc()
+		return
+	}
+	backupDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	backupSize.WithLabelValues(operation).Set(float64(size))
+}
+
+// Middleware records the number and duration of requests per Gin route.
+// Requests that match no route are counted under an empty route.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
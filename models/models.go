@@ -2,9 +2,16 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
+var (
+	ErrIDRequired       = errors.New("id is required")
+	ErrDeathBeforeBirth = errors.New("death date is before birth date")
+)
+
 type Bidprentje struct {
 	ID                string    `json:"id"`
 	Voornaam          string    `json:"voornaam"`
@@ -16,6 +23,9 @@ type Bidprentje struct {
 	Overlijdensplaats string    `json:"overlijdensplaats"`
 	Photo             bool      `json:"photo"`
 	Scans             []string  `json:"scans"`
+
+	// LastModified is set by the store whenever the content of the record changes
+	LastModified time.Time `json:"last_modified"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Bidprentje
@@ -31,6 +41,7 @@ func (b Bidprentje) MarshalJSON() ([]byte, error) {
 		Overlijdensplaats string   `json:"overlijdensplaats"`
 		Photo             bool     `json:"photo"`
 		Scans             []string `json:"scans"`
+		LastModified      string   `json:"last_modified,omitempty"`
 	}{
 		ID:                b.ID,
 		Voornaam:          b.Voornaam,
@@ -42,6 +53,7 @@ func (b Bidprentje) MarshalJSON() ([]byte, error) {
 		Overlijdensplaats: b.Overlijdensplaats,
 		Photo:             b.Photo,
 		Scans:             b.Scans,
+		LastModified:      formatTimestamp(b.LastModified),
 	})
 }
 
@@ -58,6 +70,7 @@ func (b *Bidprentje) UnmarshalJSON(data []byte) error {
 		Overlijdensplaats string   `json:"overlijdensplaats"`
 		Photo             bool     `json:"photo"`
 		Scans             []string `json:"scans"`
+		LastModified      string   `json:"last_modified"`
 	}{}
 
 	if err := json.Unmarshal(data, &aux); err != nil {
@@ -86,6 +99,32 @@ func (b *Bidprentje) UnmarshalJSON(data []byte) error {
 			return err
 		}
 	}
+	if aux.LastModified != "" {
+		b.LastModified, err = time.Parse(time.RFC3339, aux.LastModified)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTimestamp formats t as RFC 3339 in UTC, empty when t is zero
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Validate checks that a bidprentje can be stored: it needs an ID and the
+// death date, when both dates are known, may not precede the birth date
+func (b *Bidprentje) Validate() error {
+	if strings.TrimSpace(b.ID) == "" {
+		return ErrIDRequired
+	}
+	if !b.Geboortedatum.IsZero() && !b.Overlijdensdatum.IsZero() && b.Overlijdensdatum.Before(b.Geboortedatum) {
+		return ErrDeathBeforeBirth
+	}
 	return nil
 }
 
@@ -94,6 +133,95 @@ type SearchParams struct {
 	Page       int    `form:"page,default=1"`
 	PageSize   int    `form:"page_size,default=10"`
 	ExactMatch bool   `form:"exact_match"`
+	MatchMode  string `form:"match"`
+	Sort       string `form:"sort"`
+
+	// Fielded criteria, combined with each other and with Query using AND
+	Voornaam           string `form:"voornaam"`
+	Tussenvoegsel      string `form:"tussenvoegsel"`
+	Achternaam         string `form:"achternaam"`
+	Geboorteplaats     string `form:"geboorteplaats"`
+	Overlijdensplaats  string `form:"overlijdensplaats"`
+	GeboortejaarVan    int    `form:"geboortejaar_van"`
+	GeboortejaarTot    int    `form:"geboortejaar_tot"`
+	OverlijdensjaarVan int    `form:"overlijdensjaar_van"`
+	OverlijdensjaarTot int    `form:"overlijdensjaar_tot"`
+	HasPhoto           *bool  `form:"has_photo"`
+	HasScans           *bool  `form:"has_scans"`
+
+	// Place filters, as set by the facet chips of the search page. Unlike
+	// Geboorteplaats and Overlijdensplaats they only match the exact value.
+	GeboorteplaatsFilter    string `form:"geboorteplaats_filter"`
+	OverlijdensplaatsFilter string `form:"overlijdensplaats_filter"`
+
+	// Date ranges, inclusive on both ends; a zero time leaves a bound open
+	GeboortedatumVan    time.Time `form:"geboortedatum_van" time_format:"2006-01-02"`
+	GeboortedatumTot    time.Time `form:"geboortedatum_tot" time_format:"2006-01-02"`
+	OverlijdensdatumVan time.Time `form:"overlijdensdatum_van" time_format:"2006-01-02"`
+	OverlijdensdatumTot time.Time `form:"overlijdensdatum_tot" time_format:"2006-01-02"`
+
+	// Explain adds the score explanation to every hit; admins only
+	Explain bool `form:"explain"`
+}
+
+// Match modes for names and places. Fuzzy allows one edit per word,
+// phonetic matches first names and surnames that sound alike.
+const (
+	MatchFuzzy    = "fuzzy"
+	MatchExact    = "exact"
+	MatchPhonetic = "phonetic"
+)
+
+// Mode returns the match mode, falling back to ExactMatch when MatchMode
+// is not set
+func (p SearchParams) Mode() string {
+	if p.MatchMode != "" {
+		return p.MatchMode
+	}
+	if p.ExactMatch {
+		return MatchExact
+	}
+	return MatchFuzzy
+}
+
+// SortRelevance orders search results by score. It is the default for
+// searches; lists without a query fall back to ordering by ID.
+const SortRelevance = "relevance"
+
+// SortFields are the fields results can be sorted on. Prefix a field with
+// "-" to sort descending, e.g. "-geboortedatum".
+var SortFields = []string{"achternaam", "voornaam", "geboortedatum", "overlijdensdatum", "id"}
+
+// ValidSort reports whether sort is empty, SortRelevance or a, possibly
+// descending, field from SortFields
+func ValidSort(sort string) bool {
+	if sort == "" || sort == SortRelevance {
+		return true
+	}
+	field := strings.TrimPrefix(sort, "-")
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFieldCriteria reports whether any of the fielded criteria is set
+func (p SearchParams) HasFieldCriteria() bool {
+	return p.Voornaam != "" || p.Tussenvoegsel != "" || p.Achternaam != "" ||
+		p.Geboorteplaats != "" || p.Overlijdensplaats != "" ||
+		p.GeboorteplaatsFilter != "" || p.OverlijdensplaatsFilter != "" ||
+		p.GeboortejaarVan != 0 || p.GeboortejaarTot != 0 ||
+		p.OverlijdensjaarVan != 0 || p.OverlijdensjaarTot != 0 ||
+		p.HasPhoto != nil || p.HasScans != nil ||
+		!p.GeboortedatumVan.IsZero() || !p.GeboortedatumTot.IsZero() ||
+		!p.OverlijdensdatumVan.IsZero() || !p.OverlijdensdatumTot.IsZero()
+}
+
+// HasCriteria reports whether the parameters contain anything to search for
+func (p SearchParams) HasCriteria() bool {
+	return strings.TrimSpace(p.Query) != "" || p.HasFieldCriteria()
 }
 
 type PaginatedResponse struct {
@@ -101,4 +229,90 @@ type PaginatedResponse struct {
 	TotalCount int          `json:"total_count"`
 	Page       int          `json:"page"`
 	PageSize   int          `json:"page_size"`
+	Facets     *Facets      `json:"facets,omitempty"`
+	Hits       []Hit        `json:"hits,omitempty"`
+}
+
+// Hit tells why a search result matched. Hits are in the same order as the
+// items of the response.
+type Hit struct {
+	ID            string              `json:"id"`
+	Score         float64             `json:"score"`
+	MatchedFields []string            `json:"matched_fields"`
+	Fragments     map[string][]string `json:"fragments,omitempty"`   // HTML with matches in <strong>
+	Explanation   json.RawMessage     `json:"explanation,omitempty"` // Bleve score explanation
+}
+
+// FacetCount is the number of matching bidprentjes with a given value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets summarizes all matches of a search, not just the returned page.
+// Decades are identified by their first year, e.g. "1920" for 1920-1929.
+type Facets struct {
+	Geboorteplaats       []FacetCount `json:"geboorteplaats"`
+	Overlijdensplaats    []FacetCount `json:"overlijdensplaats"`
+	Geboortedecennium    []FacetCount `json:"geboortedecennium"`
+	Overlijdensdecennium []FacetCount `json:"overlijdensdecennium"`
+	Photo                []FacetCount `json:"photo"`
+	HasScans             []FacetCount `json:"has_scans"`
+}
+
+// Suggestion kinds, the fields completions are taken from. Places cover
+// both birth and death places.
+const (
+	SuggestAchternaam = "achternaam"
+	SuggestVoornaam   = "voornaam"
+	SuggestPlaats     = "plaats"
+)
+
+// Suggestion is a completion of a typed prefix
+type Suggestion struct {
+	Value string `json:"value"`
+	Field string `json:"field"`
+	Count int    `json:"count"`
+}
+
+// SuggestResponse is returned by the suggest endpoint
+type SuggestResponse struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// ErrorResponse is the body returned by the JSON API for 4xx and 5xx responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+const (
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ReindexJob describes a rebuild of the search index. Its status is one of
+// the import statuses.
+type ReindexJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Documents  int        `json:"documents"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ImportJob describes the progress of a CSV import
+type ImportJob struct {
+	ID              string     `json:"id"`
+	Status          string     `json:"status"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	TotalRecords    int        `json:"total_records"`
+	TotalChunks     int        `json:"total_chunks"`
+	ChunksDone      int        `json:"chunks_done"`
+	RecordsImported int        `json:"records_imported"`
+	RecordsSkipped  int        `json:"records_skipped"`
+	Errors          []string   `json:"errors"`
 }
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"bidprentjes-api/a2a"
+	"bidprentjes-api/models"
+)
+
+// ProcessA2AUpload processes an A2A XML file with one or more documents and
+// adds them to the index, like ProcessCSVUpload
+func (s *Store) ProcessA2AUpload(ctx context.Context, reader io.Reader, scanMap map[string][]string) (int, error) {
+	return s.ProcessA2AUploadWithProgress(ctx, reader, scanMap, nil)
+}
+
+// ProcessA2AUploadWithProgress processes an A2A XML file like
+// ProcessA2AUpload and calls progress, when not nil, after every chunk that
+// has been stored. Scans listed in scanMap replace those in the documents.
+func (s *Store) ProcessA2AUploadWithProgress(ctx context.Context, reader io.Reader, scanMap map[string][]string, progress func(ImportProgress)) (int, error) {
+	startTime := time.Now()
+	defer func() {
+		slog.DebugContext(ctx, "Processed A2A upload", "duration", time.Since(startTime))
+	}()
+
+	// Read all documents first
+	var docs []*a2a.Document
+	err := a2a.Decode(reader, func(doc *a2a.Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read A2A documents", "error", err)
+		return 0, fmt.Errorf("error reading A2A: %v", err)
+	}
+
+	return s.importRecords(ctx, len(docs), func(i int) (*models.Bidprentje, []string) {
+		b, problems, err := docs[i].Bidprentje()
+		if err != nil {
+			return nil, []string{fmt.Sprintf("document %d: %v", i+1, err)}
+		}
+		b.ID = normalizeID(b.ID)
+		if scans, ok := scanMap[b.ID]; ok {
+			b.Scans = scans
+		}
+		return b, problems
+	}, progress)
+}
+
+// isXML reports whether data looks like an XML document rather than a CSV
+func isXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("<"))
+}
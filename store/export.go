@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// exportPageSize is the number of records read from the index at once
+const exportPageSize = 1000
+
+// ErrExportTooLarge is returned by Export when more records match than may
+// be exported
+var ErrExportTooLarge = errors.New("too many records to export")
+
+// Export calls visit for every bidprentje matching params, in the order of
+// params.Sort. Without criteria all bidprentjes are exported, like List.
+// Exports are never ordered by relevance: the index is paged by the sort
+// values of the last hit, which scores cannot be used for, so matches are
+// ordered by ID instead.
+// When more than limit records match, ErrExportTooLarge is returned before
+// visit is called; a limit of 0 exports everything. The index is read in
+// pages, so visit may write to a slow client without blocking imports. The
+// export stops when ctx is done.
+func (s *Store) Export(ctx context.Context, params models.SearchParams, limit int, visit func(*models.Bidprentje) error) error {
+	startTime := time.Now()
+	records := 0
+	var searchQuery query.Query = bleve.NewMatchAllQuery()
+	if params.HasCriteria() {
+		searchQuery = s.buildSearchQuery(params)
+	}
+	order := sortOrder(params.Sort, false)
+
+	var after []string
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, total, err := s.exportPage(searchQuery, order, after)
+		if err != nil {
+			return err
+		}
+		if after == nil && limit > 0 && total > limit {
+			return fmt.Errorf("%w: %d records match, at most %d can be exported", ErrExportTooLarge, total, limit)
+		}
+
+		for i := range page.items {
+			if err := visit(&page.items[i]); err != nil {
+				return err
+			}
+		}
+		records += len(page.items)
+		if page.last == nil {
+			slog.InfoContext(ctx, "Export", "query", params.Query, criteriaAttr(params), "records", records, "duration", time.Since(startTime))
+			return nil
+		}
+		after = page.last
+	}
+}
+
+// exportPageResult holds one page of an export and the sort values to
+// continue after, which are nil on the last page
+type exportPageResult struct {
+	items []models.Bidprentje
+	last  []string
+}
+
+// exportPage reads the page of matches following the sort values after
+func (s *Store) exportPage(searchQuery query.Query, order search.SortOrder, after []string) (exportPageResult, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searchRequest := bleve.NewSearchRequest(searchQuery)
+	searchRequest.Size = exportPageSize
+	searchRequest.SortByCustom(order)
+	if after != nil {
+		searchRequest.SetSearchAfter(after)
+	}
+
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil {
+		return exportPageResult{}, 0, fmt.Errorf("export search failed: %v", err)
+	}
+
+	var page exportPageResult
+	for _, hit := range searchResults.Hits {
+		if b, exists := s.data[hit.ID]; exists {
+			page.items = append(page.items, *b)
+		}
+	}
+	if len(searchResults.Hits) == exportPageSize {
+		page.last = searchResults.Hits[len(searchResults.Hits)-1].Sort
+	}
+	return page, int(searchResults.Total), nil
+}
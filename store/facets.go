@@ -0,0 +1,83 @@
+package store
+
+import (
+	"sort"
+	"strconv"
+
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+const (
+	placeFacetSize  = 10
+	decadeFacetSize = 50
+)
+
+// decade returns the first year of the decade containing year, as used by
+// the decade facet fields
+func decade(year int) string {
+	return strconv.Itoa(year - year%10)
+}
+
+// addFacetRequests asks for the counts shown next to the search results
+func addFacetRequests(req *bleve.SearchRequest) {
+	req.AddFacet("geboorteplaats", bleve.NewFacetRequest("geboorteplaats_facet", placeFacetSize))
+	req.AddFacet("overlijdensplaats", bleve.NewFacetRequest("overlijdensplaats_facet", placeFacetSize))
+	req.AddFacet("geboortedecennium", bleve.NewFacetRequest("geboortedecennium", decadeFacetSize))
+	req.AddFacet("overlijdensdecennium", bleve.NewFacetRequest("overlijdensdecennium", decadeFacetSize))
+	req.AddFacet("photo", bleve.NewFacetRequest("photo", 2))
+	req.AddFacet("has_scans", bleve.NewFacetRequest("has_scans", 2))
+}
+
+// facetsFromResults converts the facet results of a search into the
+// response model. Places are ordered by count, decades chronologically.
+func facetsFromResults(results search.FacetResults) *models.Facets {
+	facets := &models.Facets{
+		Geboorteplaats:       termCounts(results["geboorteplaats"]),
+		Overlijdensplaats:    termCounts(results["overlijdensplaats"]),
+		Geboortedecennium:    termCounts(results["geboortedecennium"]),
+		Overlijdensdecennium: termCounts(results["overlijdensdecennium"]),
+		Photo:                boolCounts(results["photo"]),
+		HasScans:             boolCounts(results["has_scans"]),
+	}
+
+	byValue := func(counts []models.FacetCount) {
+		sort.Slice(counts, func(i, j int) bool { return counts[i].Value < counts[j].Value })
+	}
+	byValue(facets.Geboortedecennium)
+	byValue(facets.Overlijdensdecennium)
+
+	return facets
+}
+
+func termCounts(result *search.FacetResult) []models.FacetCount {
+	counts := []models.FacetCount{}
+	if result == nil || result.Terms == nil {
+		return counts
+	}
+	for _, term := range result.Terms.Terms() {
+		if term.Term == "" {
+			continue
+		}
+		counts = append(counts, models.FacetCount{Value: term.Term, Count: term.Count})
+	}
+	return counts
+}
+
+// boolCounts translates the T and F terms of a boolean field into true
+// and false, with true first
+func boolCounts(result *search.FacetResult) []models.FacetCount {
+	counts := termCounts(result)
+	for i := range counts {
+		switch counts[i].Value {
+		case "T":
+			counts[i].Value = "true"
+		case "F":
+			counts[i].Value = "false"
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Value > counts[j].Value })
+	return counts
+}
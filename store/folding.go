@@ -0,0 +1,60 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// spellingFoldingName is the token filter that folds diacritics and
+// historical spellings, used by the "bidprentje_folded" analyzer
+const spellingFoldingName = "spelling_folding"
+
+// umlautExpansion writes German umlauts the way they are spelled without
+// them, so Müller and Mueller fold to the same term
+var umlautExpansion = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"Ä", "ae", "Ö", "oe", "Ü", "ue",
+)
+
+// historicalSpellings maps old Dutch spellings to a single form: ij and y,
+// ck and k, and ae and aa are written interchangeably in older records
+var historicalSpellings = strings.NewReplacer(
+	"ij", "y",
+	"ck", "k",
+	"ae", "aa",
+)
+
+// foldSpelling lowercases term, expands umlauts, removes other diacritics
+// and normalizes historical spellings
+func foldSpelling(term string) string {
+	return historicalSpellings.Replace(foldDiacritics(term))
+}
+
+// foldDiacritics lowercases term, expands umlauts and removes other
+// diacritics. Unlike foldSpelling it keeps every letter sequence, so the
+// prefix of a word folds to the prefix of the folded word.
+func foldDiacritics(term string) string {
+	folded := umlautExpansion.Replace(strings.ToLower(term))
+	return string(asciiFolding.Filter([]byte(folded)))
+}
+
+// SpellingFoldingFilter applies foldSpelling to every token
+type SpellingFoldingFilter struct{}
+
+func (f *SpellingFoldingFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	for _, token := range input {
+		token.Term = []byte(foldSpelling(string(token.Term)))
+	}
+	return input
+}
+
+func spellingFoldingConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+	return &SpellingFoldingFilter{}, nil
+}
+
+func init() {
+	// Registered globally, as opened indexes look the filter up by name
+	registry.RegisterTokenFilter(spellingFoldingName, spellingFoldingConstructor)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// HarvestPage is one page of bidprentjes selected by last-modified time
+type HarvestPage struct {
+	Items []models.Bidprentje
+	// Total is the number of matching bidprentjes on all pages
+	Total int
+	// Last is the ID to continue after, empty on the last page
+	Last string
+}
+
+// Harvest returns up to size bidprentjes last modified between from and
+// until, both inclusive and left open when zero, in ID order following the
+// ID after. Paging by ID keeps the pages stable while records are modified.
+func (s *Store) Harvest(from, until time.Time, after string, size int) (*HarvestPage, error) {
+	// The index only supports dates in a limited range, bounds outside it
+	// select nothing or everything
+	if from.After(query.MaxRFC3339CompatibleTime) || (!until.IsZero() && until.Before(query.MinRFC3339CompatibleTime)) {
+		return &HarvestPage{Items: []models.Bidprentje{}}, nil
+	}
+	if from.Before(query.MinRFC3339CompatibleTime) {
+		from = time.Time{}
+	}
+	if until.After(query.MaxRFC3339CompatibleTime) {
+		until = time.Time{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var harvestQuery query.Query = bleve.NewMatchAllQuery()
+	if !from.IsZero() || !until.IsZero() {
+		inclusive := true
+		dateQuery := bleve.NewDateRangeInclusiveQuery(from, until, &inclusive, &inclusive)
+		dateQuery.SetField("last_modified")
+		harvestQuery = dateQuery
+	}
+
+	// Count all matches separately, the total of a page depends on after
+	countRequest := bleve.NewSearchRequest(harvestQuery)
+	countRequest.Size = 0
+	countResults, err := s.index.Search(countRequest)
+	if err != nil {
+		return nil, fmt.Errorf("harvest search failed: %v", err)
+	}
+
+	// One more than asked for tells whether another page follows
+	searchRequest := bleve.NewSearchRequest(harvestQuery)
+	searchRequest.Size = size + 1
+	searchRequest.SortByCustom(search.SortOrder{&search.SortDocID{}})
+	if after != "" {
+		searchRequest.SetSearchAfter([]string{after})
+	}
+
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("harvest search failed: %v", err)
+	}
+
+	hits := searchResults.Hits
+	page := &HarvestPage{Total: int(countResults.Total)}
+	if len(hits) > size {
+		hits = hits[:size]
+		page.Last = hits[size-1].ID
+	}
+	page.Items = make([]models.Bidprentje, 0, len(hits))
+	for _, hit := range hits {
+		if b, exists := s.data[hit.ID]; exists {
+			page.Items = append(page.Items, *b)
+		}
+	}
+	return page, nil
+}
+
+// EarliestModified returns the oldest last-modified time of all
+// bidprentjes, the zero time when there are none
+func (s *Store) EarliestModified() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searchRequest := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+	searchRequest.Size = 1
+	searchRequest.SortBy([]string{"last_modified"})
+	searchRequest.Fields = []string{"last_modified"}
+
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil || len(searchResults.Hits) == 0 {
+		return time.Time{}
+	}
+	return getDateField(searchResults.Hits[0].Fields, "last_modified")
+}
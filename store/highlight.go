@@ -0,0 +1,157 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2/search"
+	htmlFormatter "github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	simpleFragmenter "github.com/blevesearch/bleve/v2/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/v2/search/highlight/highlighter/simple"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+const (
+	fragmentSize = 200
+	maxFragments = 3
+)
+
+// highlighter marks the matched terms of a stored field in bold
+var highlighter = simpleHighlighter.NewHighlighter(
+	simpleFragmenter.NewFragmenter(fragmentSize),
+	htmlFormatter.NewFragmentFormatter("<strong>", "</strong>"),
+	"…",
+)
+
+// wholeValueFields are indexed as a single term built from the fields they
+// map to, so a match on them covers those fields completely
+var wholeValueFields = map[string][]string{
+	"achternaam_volledig":     {"tussenvoegsel", "achternaam"},
+	"achternaam_samengesteld": {"tussenvoegsel", "achternaam"},
+	"tussenvoegsel_norm":      {"tussenvoegsel"},
+}
+
+// newHit returns why a search result matched. Matches on the folded,
+// phonetic and whole-name fields are reported on the stored field they
+// were derived from, so "Mueller" found through achternaam_folded
+// highlights the achternaam.
+func (s *Store) newHit(match *search.DocumentMatch, explain bool) (models.Hit, error) {
+	hit := models.Hit{
+		ID:            match.ID,
+		Score:         match.Score,
+		MatchedFields: []string{},
+	}
+
+	if explain && match.Expl != nil {
+		explanation, err := json.Marshal(match.Expl)
+		if err != nil {
+			return hit, fmt.Errorf("failed to encode score explanation: %v", err)
+		}
+		hit.Explanation = explanation
+	}
+
+	if len(match.Locations) == 0 {
+		return hit, nil
+	}
+
+	doc, err := s.index.Document(match.ID)
+	if err != nil {
+		return hit, fmt.Errorf("failed to load document for highlighting: %v", err)
+	}
+	if doc == nil {
+		return hit, nil
+	}
+
+	values := make(map[string]string)
+	doc.VisitFields(func(f index.Field) {
+		if _, ok := f.(index.TextField); ok && len(f.ArrayPositions()) == 0 {
+			values[f.Name()] = string(f.Value())
+		}
+	})
+
+	locations := make(search.FieldTermLocationMap)
+	add := func(field, term string, location *search.Location) {
+		if locations[field] == nil {
+			locations[field] = make(search.TermLocationMap)
+		}
+		locations[field][term] = append(locations[field][term], location)
+	}
+
+	for field, terms := range match.Locations {
+		switch {
+		case wholeValueFields[field] != nil:
+			for _, source := range wholeValueFields[field] {
+				if value := values[source]; value != "" {
+					add(source, value, &search.Location{Pos: 1, Start: 0, End: uint64(len(value))})
+				}
+			}
+		case strings.HasSuffix(field, "_phonetic"):
+			source := strings.TrimSuffix(field, "_phonetic")
+			for term := range terms {
+				for _, location := range phoneticLocations(values[source], term) {
+					add(source, term, location)
+				}
+			}
+		default:
+			// The folded fields are analyzed from the same text, so
+			// their offsets apply to the stored field as well
+			source := strings.TrimSuffix(field, "_folded")
+			for term, termLocations := range terms {
+				for _, location := range termLocations {
+					add(source, term, location)
+				}
+			}
+		}
+	}
+
+	match.Locations = locations
+	for field := range locations {
+		fragments := highlighter.BestFragmentsInField(match, doc, field, maxFragments)
+		if len(fragments) == 0 {
+			continue
+		}
+		if hit.Fragments == nil {
+			hit.Fragments = make(map[string][]string)
+		}
+		hit.Fragments[field] = fragments
+		hit.MatchedFields = append(hit.MatchedFields, field)
+	}
+	sort.Strings(hit.MatchedFields)
+
+	return hit, nil
+}
+
+// phoneticLocations returns the locations of the words in value with the
+// phonetic key term
+func phoneticLocations(value, term string) []*search.Location {
+	var locations []*search.Location
+	pos, start := 0, -1
+	for i, r := range value + " " {
+		if !isNameSeparator(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		word := value[start:i]
+		if key := phoneticKey(word); key != "" {
+			pos++
+			if key == term {
+				locations = append(locations, &search.Location{
+					Pos:   uint64(pos),
+					Start: uint64(start),
+					End:   uint64(i),
+				})
+			}
+		}
+		start = -1
+	}
+	return locations
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"bidprentjes-api/logging"
+	"bidprentjes-api/models"
+)
+
+// maxFinishedImports is the number of finished import jobs kept for querying
+const maxFinishedImports = 50
+
+// ErrImportRunning is returned by StartImport while another import is running
+var ErrImportRunning = errors.New("another import is already running")
+
+// ImportJob tracks a CSV import running in the background
+type ImportJob struct {
+	mu      sync.Mutex
+	state   models.ImportJob
+	changed chan struct{}
+}
+
+// Snapshot returns a copy of the current job state
+func (j *ImportJob) Snapshot() models.ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshotLocked()
+}
+
+// Watch returns the current job state together with a channel that is
+// closed as soon as the state changes
+func (j *ImportJob) Watch() (models.ImportJob, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshotLocked(), j.changed
+}
+
+func (j *ImportJob) snapshotLocked() models.ImportJob {
+	state := j.state
+	state.Errors = append([]string{}, j.state.Errors...)
+	return state
+}
+
+// update applies fn to the job state and wakes up all watchers
+func (j *ImportJob) update(fn func(state *models.ImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.state)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func newImportID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// StartImport imports a bidprentjes CSV or A2A XML file and an optional
+// scans CSV in the background. Only one import can run at a time.
+func (s *Store) StartImport(ctx context.Context, data, scansData []byte) (*ImportJob, error) {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+
+	for _, job := range s.imports {
+		if job.Snapshot().Status == models.ImportStatusRunning {
+			return nil, ErrImportRunning
+		}
+	}
+
+	job := &ImportJob{
+		state: models.ImportJob{
+			ID:        newImportID(),
+			Status:    models.ImportStatusRunning,
+			StartedAt: time.Now().UTC(),
+			Errors:    []string{},
+		},
+		changed: make(chan struct{}),
+	}
+	s.imports[job.state.ID] = job
+	s.pruneImportsLocked()
+
+	go s.runImport(ctx, job, data, scansData)
+
+	return job, nil
+}
+
+func (s *Store) runImport(ctx context.Context, job *ImportJob, data, scansData []byte) {
+	// Everything logged for the import carries its job ID
+	ctx = logging.With(ctx, slog.String("job_id", job.Snapshot().ID))
+	slog.InfoContext(ctx, "Starting import job")
+
+	var scanMap map[string][]string
+	var scanErrors []string
+	if len(scansData) > 0 {
+		var err error
+		scanMap, err = s.parseScans(ctx, bytes.NewReader(scansData))
+		if err != nil {
+			scanErrors = append(scanErrors, fmt.Sprintf("scans: %v", err))
+			job.update(func(state *models.ImportJob) {
+				state.Errors = appendImportErrors(state.Errors, scanErrors...)
+			})
+		}
+	}
+
+	process := s.ProcessCSVUploadWithProgress
+	if isXML(data) {
+		process = s.ProcessA2AUploadWithProgress
+	}
+
+	_, err := process(ctx, bytes.NewReader(data), scanMap, func(p ImportProgress) {
+		job.update(func(state *models.ImportJob) {
+			state.TotalRecords = p.TotalRecords
+			state.TotalChunks = p.TotalChunks
+			state.ChunksDone = p.ChunksDone
+			state.RecordsImported = p.RecordsImported
+			state.RecordsSkipped = p.RecordsSkipped
+			state.Errors = appendImportErrors(append([]string{}, scanErrors...), p.Errors...)
+		})
+	})
+
+	if err == nil && s.HasStorageConnectivity() {
+		if backupErr := s.BackupIndex(ctx); backupErr != nil {
+			slog.WarnContext(ctx, "Failed to back up index after import job", "error", backupErr)
+		}
+	}
+
+	job.update(func(state *models.ImportJob) {
+		finished := time.Now().UTC()
+		state.FinishedAt = &finished
+		if err != nil {
+			state.Status = models.ImportStatusFailed
+			state.Errors = appendImportErrors(state.Errors, err.Error())
+		} else {
+			state.Status = models.ImportStatusCompleted
+		}
+	})
+	snapshot := job.Snapshot()
+	slog.InfoContext(ctx, "Finished import job", "status", snapshot.Status, "records", snapshot.RecordsImported, "skipped", snapshot.RecordsSkipped)
+}
+
+// pruneImportsLocked drops the oldest finished jobs beyond maxFinishedImports
+func (s *Store) pruneImportsLocked() {
+	var finished []models.ImportJob
+	for _, job := range s.imports {
+		if state := job.Snapshot(); state.Status != models.ImportStatusRunning {
+			finished = append(finished, state)
+		}
+	}
+	if len(finished) <= maxFinishedImports {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].StartedAt.Before(finished[j].StartedAt)
+	})
+	for _, state := range finished[:len(finished)-maxFinishedImports] {
+		delete(s.imports, state.ID)
+	}
+}
+
+// GetImport returns the import job with the given ID
+func (s *Store) GetImport(id string) (*ImportJob, bool) {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+	job, ok := s.imports[id]
+	return job, ok
+}
+
+// ListImports returns the state of all known import jobs, newest first
+func (s *Store) ListImports() []models.ImportJob {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+
+	jobs := make([]models.ImportJob, 0, len(s.imports))
+	for _, job := range s.imports {
+		jobs = append(jobs, job.Snapshot())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	return jobs
+}
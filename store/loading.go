@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Load states of a store. A store is loading until its index has been
+// restored or built, and then ready, or failed when not even an empty
+// index could be created.
+const (
+	StateLoading = "loading"
+	StateReady   = "ready"
+	StateFailed  = "failed"
+)
+
+// StartStore creates a store backed by the storage backend at storageURL
+// like NewStore, but returns at once and loads the index in the background.
+// Until State reports StateReady only State, Status, LoadSynonyms and Close
+// may be used.
+func StartStore(ctx context.Context, storageURL string) *Store {
+	s := newStore(openStorage(ctx, storageURL))
+	go func() {
+		if err := s.load(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to load index", "error", err)
+			s.setState(StateFailed, err)
+			return
+		}
+		slog.InfoContext(ctx, "Index loaded", "records", s.Count())
+		s.setState(StateReady, nil)
+	}()
+	return s
+}
+
+// State returns the load state of the store and, when it failed, why
+func (s *Store) State() (string, error) {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.state, s.loadErr
+}
+
+// Ready reports whether the store has finished loading
+func (s *Store) Ready() bool {
+	state, _ := s.State()
+	return state == StateReady
+}
+
+func (s *Store) setState(state string, err error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.state = state
+	s.loadErr = err
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+)
+
+// phoneticSpellings rewrites Dutch spellings of the same sound to a single
+// form before encoding. Longer patterns are listed first.
+var phoneticSpellings = strings.NewReplacer(
+	"sch", "sg",
+	"ij", "y",
+	"ch", "g",
+	"gh", "g",
+	"ck", "k",
+	"kx", "ks",
+	"ph", "f",
+	"th", "t",
+	"dt", "t",
+	"qu", "kw",
+	"x", "ks",
+)
+
+// phoneticCodes groups consonants that sound alike or only differ by
+// final devoicing. Vowels and h are handled separately.
+var phoneticCodes = map[rune]byte{
+	'b': 'P', 'p': 'P',
+	'd': 'T', 't': 'T',
+	'f': 'F', 'v': 'F',
+	'g': 'G',
+	'k': 'K', 'q': 'K',
+	's': 'S', 'z': 'S',
+	'l': 'L',
+	'm': 'M',
+	'n': 'N',
+	'r': 'R',
+	'w': 'W',
+	'j': 'J',
+}
+
+var asciiFolding = asciifolding.New()
+
+// phoneticKey encodes a single Dutch name in the spirit of Soundex: alike
+// sounding consonants share a code, vowels are dropped after the first
+// letter and repeated codes are collapsed. Janssen, Jansen and Janssens
+// all encode as JNSN, Huijbers, Huybers and Hoebers as HPRS.
+func phoneticKey(word string) string {
+	folded := strings.ToLower(string(asciiFolding.Filter([]byte(word))))
+	w := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, folded)
+	if w == "" {
+		return ""
+	}
+
+	// Patronymic variants like Janssens and Janssen are the same name
+	if len(w) > 4 && strings.HasSuffix(w, "ens") {
+		w = strings.TrimSuffix(w, "s")
+	}
+	// A final sch is pronounced as s, as in Bosch
+	if strings.HasSuffix(w, "sch") {
+		w = strings.TrimSuffix(w, "ch")
+	}
+	w = phoneticSpellings.Replace(w)
+
+	var key []byte
+	var prev byte
+	for i, r := range w {
+		var code byte
+		switch r {
+		case 'a', 'e', 'i', 'o', 'u', 'y':
+			if i == 0 {
+				key = append(key, 'A')
+			}
+			prev = 0
+			continue
+		case 'h':
+			if i == 0 {
+				key = append(key, 'H')
+			}
+			continue
+		case 'c':
+			// A c sounds like s before e, i and y and like k elsewhere
+			code = 'K'
+			if i+1 < len(w) && strings.ContainsRune("eiy", rune(w[i+1])) {
+				code = 'S'
+			}
+		default:
+			code = phoneticCodes[r]
+		}
+		if code != prev {
+			key = append(key, code)
+		}
+		prev = code
+	}
+
+	return string(key)
+}
+
+// isNameSeparator reports whether r separates the words of a name
+func isNameSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '\'' || r == ',' || r == '.'
+}
+
+// phoneticKeys encodes every word of a name, separated by spaces
+func phoneticKeys(name string) string {
+	var keys []string
+	for _, word := range strings.FieldsFunc(name, isNameSeparator) {
+		if key := phoneticKey(word); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(keys, " ")
+}
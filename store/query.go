@@ -0,0 +1,414 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// buildSearchQuery combines the free-text query and all fielded criteria
+// of params with AND
+func (s *Store) buildSearchQuery(params models.SearchParams) query.Query {
+	var conjuncts []query.Query
+
+	if strings.TrimSpace(params.Query) != "" {
+		conjuncts = append(conjuncts, freeTextQuery(params.Query, params.Mode(), s.currentSynonyms()))
+	}
+
+	fields := []struct {
+		field string
+		value string
+	}{
+		{"geboorteplaats", params.Geboorteplaats},
+		{"overlijdensplaats", params.Overlijdensplaats},
+	}
+	for _, f := range fields {
+		if q := s.fieldQuery(f.field, f.value, params.Mode()); q != nil {
+			conjuncts = append(conjuncts, q)
+		}
+	}
+	filters := []struct {
+		field string
+		value string
+	}{
+		{"geboorteplaats_facet", params.GeboorteplaatsFilter},
+		{"overlijdensplaats_facet", params.OverlijdensplaatsFilter},
+	}
+	for _, f := range filters {
+		if q := facetFilterQuery(f.field, f.value); q != nil {
+			conjuncts = append(conjuncts, q)
+		}
+	}
+	if q := s.firstNameQuery(params.Voornaam, params.Mode()); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+	if q := s.surnameQuery(params.Achternaam, params.Mode()); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+	if q := tussenvoegselQuery(params.Tussenvoegsel); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+
+	if q := yearRangeQuery("geboortejaar", params.GeboortejaarVan, params.GeboortejaarTot); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+	if q := yearRangeQuery("overlijdensjaar", params.OverlijdensjaarVan, params.OverlijdensjaarTot); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+
+	if q := dateRangeQuery("geboortedatum", params.GeboortedatumVan, params.GeboortedatumTot); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+	if q := dateRangeQuery("overlijdensdatum", params.OverlijdensdatumVan, params.OverlijdensdatumTot); q != nil {
+		conjuncts = append(conjuncts, q)
+	}
+
+	if params.HasPhoto != nil {
+		q := query.NewBoolFieldQuery(*params.HasPhoto)
+		q.SetField("photo")
+		conjuncts = append(conjuncts, q)
+	}
+	if params.HasScans != nil {
+		q := query.NewBoolFieldQuery(*params.HasScans)
+		q.SetField("has_scans")
+		conjuncts = append(conjuncts, q)
+	}
+
+	if len(conjuncts) == 1 {
+		return conjuncts[0]
+	}
+	return query.NewConjunctionQuery(conjuncts)
+}
+
+// facetFilterQuery matches the exact value of a facet field, so a filter
+// finds the records its facet count was made of
+func facetFilterQuery(field, value string) query.Query {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	q := query.NewTermQuery(value)
+	q.SetField(field)
+	return q
+}
+
+// fieldQuery matches all words of value in a single field. The fuzzy mode
+// allows one edit per word; the phonetic mode compares the phonetic keys of
+// first names and surnames and matches other fields exactly.
+func (s *Store) fieldQuery(field, value, mode string) query.Query {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if mode == models.MatchPhonetic && (field == "voornaam" || field == "achternaam") {
+		keys := phoneticKeys(value)
+		if keys == "" {
+			return nil
+		}
+		q := query.NewMatchQuery(keys)
+		q.SetField(field + "_phonetic")
+		q.SetOperator(query.MatchQueryOperatorAnd)
+		return q
+	}
+
+	// Values consisting only of stop words are not indexed and would match
+	// nothing
+	if analyzer := s.index.Mapping().AnalyzerNamed("bidprentje"); analyzer != nil {
+		if len(analyzer.Analyze([]byte(value))) == 0 {
+			return nil
+		}
+	}
+
+	// Fuzzy matches also ignore diacritics and historical spellings, exact
+	// matches honour them
+	q := query.NewMatchQuery(value)
+	q.SetField(field)
+	q.SetOperator(query.MatchQueryOperatorAnd)
+	if mode == models.MatchFuzzy {
+		q.SetField(field + "_folded")
+		q.SetFuzziness(1)
+	}
+	return q
+}
+
+// firstNameQuery matches every word of value as a first name. Known
+// variants from the synonym dictionary match as well, with a lower boost
+// than the name as typed.
+func (s *Store) firstNameQuery(value, mode string) query.Query {
+	synonyms := s.currentSynonyms()
+
+	var conjuncts []query.Query
+	for _, word := range strings.Fields(value) {
+		literal := s.fieldQuery("voornaam", word, mode)
+		variants := synonyms.Variants(word)
+		if len(variants) == 0 {
+			if literal != nil {
+				conjuncts = append(conjuncts, literal)
+			}
+			continue
+		}
+
+		alternatives := variantQueries(variants, 0.5)
+		if literal != nil {
+			alternatives = append(alternatives, literal)
+		}
+		conjuncts = append(conjuncts, query.NewDisjunctionQuery(alternatives))
+	}
+
+	switch len(conjuncts) {
+	case 0:
+		return nil
+	case 1:
+		return conjuncts[0]
+	}
+	return query.NewConjunctionQuery(conjuncts)
+}
+
+// variantQueries matches each first-name variant literally with boost
+func variantQueries(variants []string, boost float64) []query.Query {
+	queries := make([]query.Query, 0, len(variants))
+	for _, variant := range variants {
+		q := query.NewMatchQuery(variant)
+		q.SetField("voornaam")
+		q.SetOperator(query.MatchQueryOperatorAnd)
+		q.SetBoost(boost)
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// surnameQuery matches a surname however it is typed: "Berg", "van den
+// Berg", "Vandenberg" or "Berg, van den". The surname proper is matched
+// in the given mode, the whole name as written or in one word is matched
+// literally and ranks higher.
+func (s *Store) surnameQuery(value, mode string) query.Query {
+	name := parseSurname("", value)
+	if name.Name == "" {
+		return nil
+	}
+
+	var queries []query.Query
+	if q := s.fieldQuery("achternaam", name.Name, mode); q != nil {
+		queries = append(queries, q)
+	}
+
+	full := query.NewTermQuery(name.FullName())
+	full.SetField("achternaam_volledig")
+	full.SetBoost(2.0)
+	queries = append(queries, full)
+
+	if concatenated := name.Concatenated(); concatenated != "" {
+		q := query.NewTermQuery(concatenated)
+		q.SetField("achternaam_samengesteld")
+		q.SetBoost(2.0)
+		queries = append(queries, q)
+	}
+
+	return query.NewDisjunctionQuery(queries)
+}
+
+// tussenvoegselQuery matches the particles of a surname, whether they were
+// recorded as tussenvoegsel or in front of the achternaam
+func tussenvoegselQuery(value string) query.Query {
+	particles := strings.Join(strings.Fields(strings.ToLower(value)), " ")
+	if particles == "" {
+		return nil
+	}
+
+	q := query.NewTermQuery(particles)
+	q.SetField("tussenvoegsel_norm")
+	return q
+}
+
+// yearRangeQuery matches years between from and to inclusive, either bound
+// may be zero to leave it open
+func yearRangeQuery(field string, from, to int) query.Query {
+	if from == 0 && to == 0 {
+		return nil
+	}
+
+	inclusive := true
+	var min, max *float64
+	if from != 0 {
+		v := float64(from)
+		min = &v
+	}
+	if to != 0 {
+		v := float64(to)
+		max = &v
+	}
+
+	q := query.NewNumericRangeInclusiveQuery(min, max, &inclusive, &inclusive)
+	q.SetField(field)
+	return q
+}
+
+// dateRangeQuery matches dates between from and to inclusive, either bound
+// may be the zero time to leave it open
+func dateRangeQuery(field string, from, to time.Time) query.Query {
+	if from.IsZero() && to.IsZero() {
+		return nil
+	}
+
+	inclusive := true
+	q := query.NewDateRangeInclusiveQuery(from, to, &inclusive, &inclusive)
+	q.SetField(field)
+	return q
+}
+
+// dateTermQueries matches a free-text term that looks like a year or a
+// YYYY-MM-DD date against the birth and death dates
+func dateTermQueries(term string) []query.Query {
+	var queries []query.Query
+	if len(term) == 4 {
+		if year, err := strconv.Atoi(term); err == nil && year > 0 {
+			for _, field := range []string{"geboortejaar", "overlijdensjaar"} {
+				q := yearRangeQuery(field, year, year)
+				q.(*query.NumericRangeQuery).SetBoost(8.0)
+				queries = append(queries, q)
+			}
+		}
+	} else if date, err := time.Parse("2006-01-02", term); err == nil {
+		for _, field := range []string{"geboortedatum", "overlijdensdatum"} {
+			q := dateRangeQuery(field, date, date)
+			q.(*query.DateRangeQuery).SetBoost(3.0)
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// freeTextQuery searches text across all fields, combining the fields with
+// OR. Terms that are known first names also match their variants.
+func freeTextQuery(text, mode string, synonyms *Synonyms) query.Query {
+	// Create a multi-field query that searches across all text fields
+	queryStr := strings.TrimSpace(text)
+
+	// Create individual field queries
+	var queries []query.Query
+
+	if mode == models.MatchExact || mode == models.MatchPhonetic {
+		// For exact matches, only use exact match queries with high boost
+		exactFields := []struct {
+			field string
+			boost float64
+		}{
+			{"id", 2.0},
+			{"achternaam", 8.0},
+			{"voornaam", 5.0},
+			{"geboorteplaats", 3.0},
+			{"overlijdensplaats", 3.0},
+			{"scans", 10.0},
+		}
+
+		for _, f := range exactFields {
+			q := query.NewMatchQuery(queryStr)
+			q.SetField(f.field)
+			q.SetBoost(f.boost)
+			queries = append(queries, q)
+		}
+
+		// Names that sound alike match too, below the literal spelling
+		if keys := phoneticKeys(queryStr); mode == models.MatchPhonetic && keys != "" {
+			phoneticFields := []struct {
+				field string
+				boost float64
+			}{
+				{"achternaam_phonetic", 4.0},
+				{"voornaam_phonetic", 2.5},
+			}
+			for _, f := range phoneticFields {
+				q := query.NewMatchQuery(keys)
+				q.SetField(f.field)
+				q.SetBoost(f.boost)
+				queries = append(queries, q)
+			}
+		}
+	} else {
+		// For fuzzy matches, split query into terms and create fuzzy queries for each
+		terms := strings.Fields(queryStr)
+		fields := []struct {
+			field  string
+			boost  float64
+			folded bool
+		}{
+			{"id", 2.0, false},
+			{"achternaam", 8.0, true},
+			{"voornaam", 5.0, true},
+			{"geboorteplaats", 3.0, true},
+			{"overlijdensplaats", 3.0, true},
+			{"scans", 2.0, false},
+		}
+
+		// Create a fuzzy query for each term in each field, names and places
+		// are compared in their folded form
+		for _, term := range terms {
+			for _, f := range fields {
+				q := query.NewFuzzyQuery(term)
+				q.SetField(f.field)
+				if f.folded {
+					q = query.NewFuzzyQuery(foldSpelling(term))
+					q.SetField(f.field + "_folded")
+				}
+				q.SetBoost(f.boost)
+				q.SetFuzziness(1)
+				queries = append(queries, q)
+			}
+		}
+	}
+
+	for _, term := range strings.Fields(queryStr) {
+		queries = append(queries, variantQueries(synonyms.Variants(term), 1.0)...)
+	}
+
+	// Surnames with particles match however they are written, see surnameQuery
+	if concatenated := concatenateName(queryStr); concatenated != "" {
+		q := query.NewTermQuery(concatenated)
+		q.SetField("achternaam_samengesteld")
+		q.SetBoost(8.0)
+		queries = append(queries, q)
+	}
+	full := query.NewTermQuery(parseSurname("", queryStr).FullName())
+	full.SetField("achternaam_volledig")
+	full.SetBoost(8.0)
+	queries = append(queries, full)
+
+	// Years and dates are matched as ranges on the numeric and date fields
+	for _, term := range strings.Fields(queryStr) {
+		queries = append(queries, dateTermQueries(term)...)
+	}
+
+	// Combine all queries with OR
+	return query.NewDisjunctionQuery(queries)
+}
+
+// sortOrder translates a sort parameter into the index sort order. Ties
+// are broken by ID so pages never overlap. Relevance only applies when
+// there is a query to score against.
+func sortOrder(sort string, scored bool) search.SortOrder {
+	field := strings.TrimPrefix(sort, "-")
+	desc := strings.HasPrefix(sort, "-")
+
+	var primary search.SearchSort
+	switch field {
+	case "achternaam", "voornaam":
+		primary = &search.SortField{Field: field + "_sort", Desc: desc, Type: search.SortFieldAsString, Missing: search.SortFieldMissingLast}
+	case "geboortedatum", "overlijdensdatum":
+		primary = &search.SortField{Field: field, Desc: desc, Type: search.SortFieldAsDate, Missing: search.SortFieldMissingLast}
+	case "id":
+		return search.SortOrder{&search.SortDocID{Desc: desc}}
+	default:
+		if !scored {
+			return search.SortOrder{&search.SortDocID{}}
+		}
+		primary = &search.SortScore{Desc: true}
+	}
+
+	return search.SortOrder{primary, &search.SortDocID{}}
+}
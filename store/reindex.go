@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"bidprentjes-api/logging"
+	"bidprentjes-api/models"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// reindexBatchSize is the number of documents written to a new index at once
+const reindexBatchSize = 1000
+
+// ErrReindexRunning is returned by StartReindex while a reindex is running
+var ErrReindexRunning = errors.New("a reindex is already running")
+
+// StartReindex runs Reindex in the background. Only one reindex can run at
+// a time; its progress is reported by ReindexStatus.
+func (s *Store) StartReindex(ctx context.Context) (models.ReindexJob, error) {
+	s.reindexMu.Lock()
+	defer s.reindexMu.Unlock()
+
+	if s.reindexJob != nil && s.reindexJob.Status == models.ImportStatusRunning {
+		return models.ReindexJob{}, ErrReindexRunning
+	}
+	job := &models.ReindexJob{
+		ID:        newImportID(),
+		Status:    models.ImportStatusRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	s.reindexJob = job
+
+	go func() {
+		ctx := logging.With(ctx, slog.String("job_id", job.ID))
+		err := s.Reindex(ctx)
+
+		s.reindexMu.Lock()
+		defer s.reindexMu.Unlock()
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		job.Documents = s.Count()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to reindex", "error", err)
+			job.Status = models.ImportStatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = models.ImportStatusCompleted
+		}
+	}()
+
+	return *job, nil
+}
+
+// ReindexStatus returns the state of the last reindex started with
+// StartReindex, and false when none was started
+func (s *Store) ReindexStatus() (models.ReindexJob, bool) {
+	s.reindexMu.Lock()
+	defer s.reindexMu.Unlock()
+
+	if s.reindexJob == nil {
+		return models.ReindexJob{}, false
+	}
+	return *s.reindexJob, true
+}
+
+// Reindex recreates the index with the current mapping from the in-memory
+// bidprentjes, for instance after the analyzers changed. Searches keep
+// using the current index until the new one is complete, and the current
+// index is kept when the rebuild fails. The new index is backed up when
+// storage is configured.
+func (s *Store) Reindex(ctx context.Context) error {
+	s.rebuildMu.Lock()
+	defer s.rebuildMu.Unlock()
+
+	if err := s.reindex(ctx); err != nil {
+		return err
+	}
+
+	if s.storage != nil {
+		if err := s.BackupIndex(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to back up reindexed index", "error", err)
+		}
+	}
+	return nil
+}
+
+// reindex builds a new index next to the current one from a snapshot of
+// the in-memory bidprentjes, then applies the changes made in the meantime
+// and swaps it in under a short lock
+func (s *Store) reindex(ctx context.Context) error {
+	// Records from before last-modified times were kept count as modified now
+	now := modificationTime()
+
+	s.mu.Lock()
+	snapshot := make(map[string]*models.Bidprentje, len(s.data))
+	for id, b := range s.data {
+		if b.LastModified.IsZero() {
+			// Readers may hold b, so it is replaced by a stamped copy
+			stamped := *b
+			stamped.LastModified = now
+			b = &stamped
+			s.data[id] = b
+		}
+		snapshot[id] = b
+	}
+	s.mu.Unlock()
+
+	// Records are replaced, never changed in place, so the snapshot can be
+	// read without the lock
+	buildPath := indexPath() + ".reindex"
+	index, err := newIndex(buildPath)
+	if err != nil {
+		return err
+	}
+	discard := func() {
+		index.Close()
+		os.RemoveAll(buildPath)
+	}
+
+	batch := index.NewBatch()
+	for _, b := range snapshot {
+		if err := batch.Index(b.ID, newBleveDocument(b)); err != nil {
+			discard()
+			return fmt.Errorf("failed to add document to batch: %v", err)
+		}
+		if batch.Size() >= reindexBatchSize {
+			if err := index.Batch(batch); err != nil {
+				discard()
+				return fmt.Errorf("failed to reindex: %v", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := index.Batch(batch); err != nil {
+		discard()
+		return fmt.Errorf("failed to reindex: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Catch up with records created, updated or deleted during the rebuild
+	batch = index.NewBatch()
+	for id, b := range s.data {
+		if snapshot[id] != b {
+			if err := batch.Index(id, newBleveDocument(b)); err != nil {
+				discard()
+				return fmt.Errorf("failed to add document to batch: %v", err)
+			}
+		}
+	}
+	for id := range snapshot {
+		if _, exists := s.data[id]; !exists {
+			batch.Delete(id)
+		}
+	}
+	if err := index.Batch(batch); err != nil {
+		discard()
+		return fmt.Errorf("failed to reindex: %v", err)
+	}
+
+	if err := s.swapIndexLocked(index, buildPath); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Reindexed bidprentjes", "records", len(s.data), "mapping_version", mappingVersion)
+	return nil
+}
+
+// swapIndexLocked replaces the current index with the complete index at
+// path, which is moved in its place. On failure the current index is
+// reopened. The caller holds s.mu.
+func (s *Store) swapIndexLocked(index bleve.Index, path string) error {
+	if err := index.Close(); err != nil {
+		os.RemoveAll(path)
+		return fmt.Errorf("failed to close new index: %v", err)
+	}
+
+	currentPath := indexPath()
+	oldPath := currentPath + ".old"
+	os.RemoveAll(oldPath)
+	if s.index != nil {
+		if err := s.index.Close(); err != nil {
+			slog.Warn("Failed to close index before swapping", "error", err)
+		}
+	}
+
+	restore := func(cause error) error {
+		os.RemoveAll(path)
+		if _, err := os.Stat(oldPath); err == nil {
+			os.RemoveAll(currentPath)
+			os.Rename(oldPath, currentPath)
+		}
+		if s.index != nil {
+			old, err := bleve.Open(currentPath)
+			if err != nil {
+				return fmt.Errorf("%v; reopening the current index failed too: %v", cause, err)
+			}
+			s.index = old
+		}
+		return cause
+	}
+
+	if err := os.Rename(currentPath, oldPath); err != nil && !os.IsNotExist(err) {
+		return restore(fmt.Errorf("failed to move current index: %v", err))
+	}
+	if err := os.Rename(path, currentPath); err != nil {
+		return restore(fmt.Errorf("failed to move new index: %v", err))
+	}
+	opened, err := bleve.Open(currentPath)
+	if err != nil {
+		os.Rename(currentPath, path)
+		return restore(fmt.Errorf("failed to open new index: %v", err))
+	}
+
+	s.index = opened
+	os.RemoveAll(oldPath)
+	return nil
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// SitemapEntry is a bidprentje listed in the sitemap
+type SitemapEntry struct {
+	ID           string
+	LastModified time.Time
+}
+
+// SitemapPage returns the entries on page (from 1) of size bidprentjes in
+// ID order, along with the total number of bidprentjes
+func (s *Store) SitemapPage(page, size int) ([]SitemapEntry, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	searchRequest := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+	searchRequest.Size = size
+	searchRequest.From = (page - 1) * size
+	searchRequest.SortByCustom(search.SortOrder{&search.SortDocID{}})
+
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sitemap search failed: %v", err)
+	}
+
+	entries := make([]SitemapEntry, 0, len(searchResults.Hits))
+	for _, hit := range searchResults.Hits {
+		if b, exists := s.data[hit.ID]; exists {
+			entries = append(entries, SitemapEntry{ID: b.ID, LastModified: b.LastModified})
+		}
+	}
+	return entries, int(searchResults.Total), nil
+}
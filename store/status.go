@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Index sources, telling where the index was loaded from at startup
+const (
+	SourceLocalCSV      = "local_csv"
+	SourceStorageBackup = "storage_backup"
+	SourceStorageCSV    = "storage_csv"
+	SourceEmpty         = "empty"
+)
+
+// Status describes the state of the store for monitoring
+type Status struct {
+	State          string `json:"state"`
+	LoadError      string `json:"load_error,omitempty"`
+	Documents      int    `json:"documents"`
+	ValidIndex     bool   `json:"valid_index"`
+	IndexSource    string `json:"index_source,omitempty"`
+	MappingVersion string `json:"mapping_version,omitempty"`
+	// LastBackup is the time of the last successful backup by this process
+	LastBackup      *time.Time    `json:"last_backup,omitempty"`
+	LastBackupError string        `json:"last_backup_error,omitempty"`
+	Storage         StorageStatus `json:"storage"`
+}
+
+// StorageStatus tells whether the storage backend can be reached
+type StorageStatus struct {
+	Configured bool   `json:"configured"`
+	Reachable  bool   `json:"reachable"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Status returns the state of the store. The storage backend is contacted
+// to check that it can be reached. The index is only described once the
+// store is ready.
+func (s *Store) Status(ctx context.Context) Status {
+	var status Status
+	state, err := s.State()
+	status.State = state
+	if err != nil {
+		status.LoadError = err.Error()
+	}
+
+	if state == StateReady {
+		s.mu.RLock()
+		status.Documents = len(s.data)
+		status.ValidIndex = s.hasValidIndex
+		status.IndexSource = s.source
+		status.MappingVersion = s.indexMappingVersion()
+		s.mu.RUnlock()
+	}
+
+	s.backupMu.Lock()
+	if !s.lastBackup.IsZero() {
+		lastBackup := s.lastBackup
+		status.LastBackup = &lastBackup
+	}
+	if s.lastBackupErr != nil {
+		status.LastBackupError = s.lastBackupErr.Error()
+	}
+	s.backupMu.Unlock()
+
+	if s.storage != nil {
+		status.Storage.Configured = true
+		if err := s.CheckStorage(ctx); err != nil {
+			status.Storage.Error = err.Error()
+		} else {
+			status.Storage.Reachable = true
+		}
+	}
+	return status
+}
+
+// CheckIndex returns an error when the index cannot be read
+func (s *Store) CheckIndex() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.index == nil {
+		return fmt.Errorf("index is not open")
+	}
+	if _, err := s.index.DocCount(); err != nil {
+		return fmt.Errorf("index cannot be read: %v", err)
+	}
+	return nil
+}
+
+// CheckStorage returns an error when the storage backend cannot be reached
+func (s *Store) CheckStorage(ctx context.Context) error {
+	if s.storage == nil {
+		return fmt.Errorf("no storage backend available")
+	}
+	if _, err := s.storage.List(ctx, "index/"); err != nil {
+		return fmt.Errorf("storage cannot be reached: %v", err)
+	}
+	return nil
+}
+
+// recordBackup remembers the outcome of a backup for Status
+func (s *Store) recordBackup(err error) {
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+
+	s.lastBackupErr = err
+	if err == nil {
+		s.lastBackup = time.Now().UTC()
+	}
+}
@@ -2,168 +2,345 @@ package store
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"bidprentjes-api/cloud"
+	"bidprentjes-api/metrics"
 	"bidprentjes-api/models"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
 	"github.com/blevesearch/bleve/v2/analysis/lang/nl"
 	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
 	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
-	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/whitespace"
+	"github.com/blevesearch/bleve/v2/mapping"
 )
 
+// IndexDir is the directory the Bleve index is kept in. It must be set
+// before a store is created.
+var IndexDir = "/tmp"
+
 const (
-	indexPath   = "/tmp/bidprentjes.bleve"
+	indexName   = "bidprentjes.bleve"
 	indexObject = "index/bidprentjes.bleve.tar.gz"
 	csvObject   = "data/bidprentjes.csv"
 	scansCSV    = "data/scans.csv"
 )
 
+// mappingVersion is bumped whenever newIndex changes the index
+// mapping or the indexed documents change. Restored backups with another
+// version are reindexed.
+const mappingVersion = "11"
+
+// ErrExists is returned by CreateIfAbsent when the ID is already taken
+var ErrExists = errors.New("bidprentje already exists")
+
+// ErrNotFound is returned by UpdateIfExists and DeleteIfExists when no
+// bidprentje has the ID
+var ErrNotFound = errors.New("bidprentje not found")
+
+// mappingVersionKey is the internal index key holding the mapping version
+var mappingVersionKey = []byte("mapping_version")
+
 type Store struct {
 	data          map[string]*models.Bidprentje
 	index         bleve.Index
 	mu            sync.RWMutex
-	gcsClient     *cloud.StorageClient
-	bucketName    string
+	storage       cloud.Storage
 	hasValidIndex bool
+	source        string
+	state         string
+	loadErr       error
+	stateMu       sync.RWMutex
+	imports       map[string]*ImportJob
+	importsMu     sync.Mutex
+	synonyms      *Synonyms
+	synonymsPath  string
+	synonymsMu    sync.RWMutex
+
+	// The last reindex started through StartReindex, and rebuildMu, which
+	// keeps reindexes from running at the same time
+	reindexJob *models.ReindexJob
+	reindexMu  sync.Mutex
+	rebuildMu  sync.Mutex
+
+	// Outcome of the last backup, see Status
+	lastBackup    time.Time
+	lastBackupErr error
+	backupMu      sync.Mutex
 }
 
 // BleveDocument represents a document in the Bleve index
 type BleveDocument struct {
-	ID                string   `json:"id"`
-	Voornaam          string   `json:"voornaam"`
-	Achternaam        string   `json:"achternaam"`
-	Tussenvoegsel     string   `json:"tussenvoegsel"`
-	Geboortedatum     string   `json:"geboortedatum"`
-	Geboortejaar      string   `json:"geboortejaar"`
-	Geboorteplaats    string   `json:"geboorteplaats"`
-	Overlijdensdatum  string   `json:"overlijdensdatum"`
-	Overlijdensjaar   string   `json:"overlijdensjaar"`
-	Overlijdensplaats string   `json:"overlijdensplaats"`
-	Photo             bool     `json:"photo"`
-	Scans             []string `json:"scans"`
+	ID                string     `json:"id"`
+	Voornaam          string     `json:"voornaam"`
+	Achternaam        string     `json:"achternaam"`
+	Tussenvoegsel     string     `json:"tussenvoegsel"`
+	Geboortedatum     *time.Time `json:"geboortedatum,omitempty"`
+	Geboortejaar      *int       `json:"geboortejaar,omitempty"`
+	Geboorteplaats    string     `json:"geboorteplaats"`
+	Overlijdensdatum  *time.Time `json:"overlijdensdatum,omitempty"`
+	Overlijdensjaar   *int       `json:"overlijdensjaar,omitempty"`
+	Overlijdensplaats string     `json:"overlijdensplaats"`
+	Photo             bool       `json:"photo"`
+	Scans             []string   `json:"scans"`
+	HasScans          bool       `json:"has_scans"`
+	LastModified      *time.Time `json:"last_modified,omitempty"`
+
+	// Phonetic keys of every word, see phoneticKeys
+	VoornaamPhonetic   string `json:"voornaam_phonetic,omitempty"`
+	AchternaamPhonetic string `json:"achternaam_phonetic,omitempty"`
+
+	// Normalized surname variants, see parseSurname. The particles also
+	// include those written in front of the achternaam.
+	TussenvoegselNorm      string `json:"tussenvoegsel_norm,omitempty"`
+	AchternaamVolledig     string `json:"achternaam_volledig,omitempty"`
+	AchternaamSamengesteld string `json:"achternaam_samengesteld,omitempty"`
+
+	// Lowercased, untokenized copies used for sorting. Surnames sort by
+	// their sort-name, "Berg, van den".
+	VoornaamSort   string `json:"voornaam_sort,omitempty"`
+	AchternaamSort string `json:"achternaam_sort,omitempty"`
+
+	// Prefix lookup terms for suggestions, see suggestTerm
+	SuggestAchternaam []string `json:"suggest_achternaam,omitempty"`
+	SuggestVoornaam   []string `json:"suggest_voornaam,omitempty"`
+	SuggestPlaats     []string `json:"suggest_plaats,omitempty"`
+
+	// Untokenized copies used for facets
+	GeboorteplaatsFacet    string `json:"geboorteplaats_facet,omitempty"`
+	OverlijdensplaatsFacet string `json:"overlijdensplaats_facet,omitempty"`
+	Geboortedecennium      string `json:"geboortedecennium,omitempty"`
+	Overlijdensdecennium   string `json:"overlijdensdecennium,omitempty"`
+}
+
+// newBleveDocument converts a bidprentje into its index representation.
+// Unknown dates are left out of the document so range queries skip them.
+func newBleveDocument(b *models.Bidprentje) BleveDocument {
+	name := parseSurname(b.Tussenvoegsel, b.Achternaam)
+	doc := BleveDocument{
+		ID:                b.ID,
+		Voornaam:          b.Voornaam,
+		Achternaam:        b.Achternaam,
+		Tussenvoegsel:     b.Tussenvoegsel,
+		Geboorteplaats:    b.Geboorteplaats,
+		Overlijdensplaats: b.Overlijdensplaats,
+		Photo:             b.Photo,
+		Scans:             b.Scans,
+		HasScans:          len(b.Scans) > 0,
+
+		VoornaamPhonetic:   phoneticKeys(b.Voornaam),
+		AchternaamPhonetic: phoneticKeys(name.Name),
+
+		TussenvoegselNorm:      name.Particles,
+		AchternaamVolledig:     name.FullName(),
+		AchternaamSamengesteld: name.Concatenated(),
+
+		VoornaamSort:   strings.TrimSpace(b.Voornaam),
+		AchternaamSort: name.SortName(),
+
+		SuggestAchternaam: surnameSuggestTerms(name, b.Tussenvoegsel, b.Achternaam),
+		SuggestVoornaam:   suggestTerms(strings.Fields(b.Voornaam)...),
+		SuggestPlaats:     suggestTerms(b.Geboorteplaats, b.Overlijdensplaats),
+
+		GeboorteplaatsFacet:    strings.TrimSpace(b.Geboorteplaats),
+		OverlijdensplaatsFacet: strings.TrimSpace(b.Overlijdensplaats),
+	}
+	if !b.Geboortedatum.IsZero() {
+		date, year := b.Geboortedatum, b.Geboortedatum.Year()
+		doc.Geboortedatum, doc.Geboortejaar = &date, &year
+		doc.Geboortedecennium = decade(year)
+	}
+	if !b.Overlijdensdatum.IsZero() {
+		date, year := b.Overlijdensdatum, b.Overlijdensdatum.Year()
+		doc.Overlijdensdatum, doc.Overlijdensjaar = &date, &year
+		doc.Overlijdensdecennium = decade(year)
+	}
+	if !b.LastModified.IsZero() {
+		lastModified := b.LastModified
+		doc.LastModified = &lastModified
+	}
+	return doc
+}
+
+// modificationTime returns the last-modified time for records written now,
+// in whole seconds as harvesters compare them at that granularity
+func modificationTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// NewStore creates a store backed by the storage backend at storageURL
+// (see cloud.NewStorage). An empty URL runs the store in local-only mode.
+func NewStore(ctx context.Context, storageURL string) *Store {
+	return NewStoreWithStorage(ctx, openStorage(ctx, storageURL))
+}
+
+// openStorage creates the storage backend at storageURL, nil for local-only
+// mode when the URL is empty or the backend cannot be created
+func openStorage(ctx context.Context, storageURL string) cloud.Storage {
+	storage, err := cloud.NewStorage(ctx, storageURL)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to create storage backend, continuing in local-only mode", "error", err)
+		return nil
+	}
+	return storage
+}
+
+// NewStoreWithStorage creates a store using the given storage backend for
+// backups and CSV sources. A nil storage runs the store in local-only mode.
+// It returns once the index has been loaded, see StartStore for loading in
+// the background.
+func NewStoreWithStorage(ctx context.Context, storage cloud.Storage) *Store {
+	s := newStore(storage)
+	if err := s.load(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to load index", "error", err)
+		os.Exit(1)
+	}
+	s.setState(StateReady, nil)
+	return s
 }
 
-func NewStore(ctx context.Context, bucketName string) *Store {
-	// Create store instance with empty fields
+// newStore creates a store without an index, in the loading state
+func newStore(storage cloud.Storage) *Store {
 	s := &Store{
 		data:          make(map[string]*models.Bidprentje),
-		bucketName:    bucketName,
+		storage:       storage,
 		hasValidIndex: false,
+		imports:       make(map[string]*ImportJob),
+		state:         StateLoading,
 	}
-
-	// Try to initialize GCS client
-	if bucketName != "" {
-		client, err := cloud.NewStorageClient(ctx, bucketName)
-		if err != nil {
-			log.Printf("Failed to create GCS client, continuing in local-only mode: %v", err)
-		} else {
-			s.gcsClient = client
-		}
+	if synonyms, err := ParseSynonyms(bytes.NewReader(defaultSynonyms)); err == nil {
+		s.synonyms = synonyms
 	}
+	return s
+}
 
+// load fills the store: from a local CSV file, a backup in storage, a CSV
+// file in storage, or else with an empty index. An error is only returned
+// when not even an empty index can be created.
+func (s *Store) load(ctx context.Context) error {
 	// 1. First try to find and process local CSV files
 	if localFile, err := os.Open(csvObject); err == nil {
-		log.Printf("Found local bidprentjes.csv file at %s, processing...", csvObject)
+		slog.InfoContext(ctx, "Found local bidprentjes.csv, processing", "path", csvObject)
 		var scanMap map[string][]string
 		if sFile, err := os.Open(scansCSV); err == nil {
-			log.Printf("Found local scans.csv file at %s, processing...", scansCSV)
-			scanMap, _ = s.parseScans(sFile)
+			slog.InfoContext(ctx, "Found local scans.csv, processing", "path", scansCSV)
+			scanMap, _ = s.parseScans(ctx, sFile)
 			sFile.Close()
 		} else {
-			log.Printf("No local scans.csv file found at %s", scansCSV)
+			slog.InfoContext(ctx, "No local scans.csv found", "path", scansCSV)
+		}
+
+		// The records date from the file, so restarts don't make them all
+		// look modified to harvesters
+		var modified time.Time
+		if info, err := localFile.Stat(); err == nil {
+			modified = info.ModTime().UTC().Truncate(time.Second)
 		}
 
 		if err := s.createNewIndex(); err != nil {
-			log.Printf("Failed to create new index: %v", err)
+			slog.ErrorContext(ctx, "Failed to create new index", "error", err)
 		} else {
-			if _, err := s.ProcessCSVUpload(localFile, scanMap); err != nil {
-				log.Printf("Failed to process local CSV: %v", err)
+			if _, err := s.processCSV(ctx, localFile, scanMap, modified, nil); err != nil {
+				slog.ErrorContext(ctx, "Failed to process local CSV", "path", csvObject, "error", err)
 			} else {
 				s.hasValidIndex = true
+				s.source = SourceLocalCSV
 				localFile.Close()
-				return s
+				return nil
 			}
 		}
 		localFile.Close()
 	}
 
-	// 2. If no local CSV, try to restore index from GCP backup
-	if s.gcsClient != nil {
-		log.Printf("Attempting to restore index from GCP backup...")
+	// 2. If no local CSV, try to restore index from a storage backup
+	if s.storage != nil {
+		slog.InfoContext(ctx, "Attempting to restore index from storage backup")
 		if err := s.downloadIndex(ctx); err == nil {
-			log.Printf("Successfully restored index from GCP backup")
+			slog.InfoContext(ctx, "Restored index from storage backup")
 			if err := s.openExistingIndex(); err == nil {
-				if err := s.rebuildDataFromIndex(); err == nil {
+				if err := s.rebuildDataFromIndex(ctx); err != nil {
+					slog.ErrorContext(ctx, "Failed to rebuild data from restored index", "error", err)
+				} else {
+					if version := s.indexMappingVersion(); version != mappingVersion {
+						slog.InfoContext(ctx, "Restored index has an outdated mapping, reindexing", "mapping_version", version, "want_mapping_version", mappingVersion)
+						if err := s.Reindex(ctx); err != nil {
+							return fmt.Errorf("failed to reindex restored index: %v", err)
+						}
+					}
 					s.hasValidIndex = true
-					return s
+					s.source = SourceStorageBackup
+					return nil
 				}
-				log.Printf("Error rebuilding data from restored index")
 			} else {
-				log.Printf("Error opening restored index")
+				slog.ErrorContext(ctx, "Failed to open restored index", "error", err)
 			}
 		} else {
-			log.Printf("Could not download index from GCP")
+			slog.InfoContext(ctx, "Could not download index from storage", "error", err)
 		}
 	}
 
-	// 3. If no restore index found, try to download and process CSV files from GCP
-	if s.gcsClient != nil {
-		log.Printf("Checking for CSV files in GCP bucket...")
-		if reader, err := s.gcsClient.DownloadFile(ctx, csvObject); err == nil {
-			log.Printf("Found bidprentjes.csv in GCP bucket at %s, processing...", csvObject)
+	// 3. If no restore index found, try to download and process CSV files from storage
+	if s.storage != nil {
+		slog.InfoContext(ctx, "Checking for CSV files in storage")
+		if reader, err := s.storage.DownloadFile(ctx, csvObject); err == nil {
+			slog.InfoContext(ctx, "Found bidprentjes.csv in storage, processing", "path", csvObject)
 
 			var scanMap map[string][]string
-			if sReader, err := s.gcsClient.DownloadFile(ctx, scansCSV); err == nil {
-				log.Printf("Found scans.csv in GCP bucket at %s, processing...", scansCSV)
-				scanMap, _ = s.parseScans(sReader)
+			if sReader, err := s.storage.DownloadFile(ctx, scansCSV); err == nil {
+				slog.InfoContext(ctx, "Found scans.csv in storage, processing", "path", scansCSV)
+				scanMap, _ = s.parseScans(ctx, sReader)
 			} else {
-				log.Printf("No scans.csv found in GCP bucket at %s", scansCSV)
+				slog.InfoContext(ctx, "No scans.csv found in storage", "path", scansCSV)
 			}
 
 			if err := s.createNewIndex(); err != nil {
-				log.Printf("Failed to create new index: %v", err)
+				slog.ErrorContext(ctx, "Failed to create new index", "error", err)
 			} else {
-				if _, err := s.ProcessCSVUpload(reader, scanMap); err != nil {
-					log.Printf("Failed to process GCP CSV file: %v", err)
+				if _, err := s.ProcessCSVUpload(ctx, reader, scanMap); err != nil {
+					slog.ErrorContext(ctx, "Failed to process CSV file from storage", "path", csvObject, "error", err)
 				} else {
 					// Create a backup of the index after processing
-					log.Printf("Creating backup of the index...")
+					slog.InfoContext(ctx, "Creating backup of the index")
 					time.Sleep(10 * time.Second)
 					if err := s.BackupIndex(ctx); err != nil {
-						log.Printf("Warning: Failed to create immediate index backup: %v", err)
+						slog.WarnContext(ctx, "Failed to create immediate index backup", "error", err)
 					} else {
-						log.Printf("Successfully created immediate index backup")
+						slog.InfoContext(ctx, "Created immediate index backup")
 					}
 					s.hasValidIndex = true
-					return s
+					s.source = SourceStorageCSV
+					return nil
 				}
 			}
 		} else {
-			log.Printf("No bidprentjes.csv found in GCP bucket at %s: %v", csvObject, err)
+			slog.InfoContext(ctx, "No bidprentjes.csv found in storage", "path", csvObject, "error", err)
 		}
 	}
 
 	// Fallback: create a new empty index
-	log.Printf("Creating new empty index as fallback...")
+	slog.InfoContext(ctx, "Creating new empty index as fallback")
 	if err := s.createNewIndex(); err != nil {
-		log.Fatalf("Failed to create fallback index: %v", err)
+		return fmt.Errorf("failed to create fallback index: %v", err)
 	}
+	s.source = SourceEmpty
 
-	return s
+	return nil
 }
 
 // normalizeID removes surrounding whitespace and quotes from an ID
@@ -175,7 +352,7 @@ func normalizeID(id string) string {
 }
 
 // parseScans parses scan metadata from an io.Reader
-func (s *Store) parseScans(reader io.Reader) (map[string][]string, error) {
+func (s *Store) parseScans(ctx context.Context, reader io.Reader) (map[string][]string, error) {
 	csvReader := csv.NewReader(reader)
 	scans := make(map[string][]string)
 	count := 0
@@ -186,7 +363,7 @@ func (s *Store) parseScans(reader io.Reader) (map[string][]string, error) {
 			break
 		}
 		if err != nil {
-			log.Printf("Error reading scan record: %v", err)
+			slog.WarnContext(ctx, "Failed to read scan record", "error", err)
 			return scans, err
 		}
 
@@ -200,15 +377,31 @@ func (s *Store) parseScans(reader io.Reader) (map[string][]string, error) {
 		}
 	}
 
-	log.Printf("Successfully processed %d scan records for %d unique bidprentjes", count, len(scans))
+	slog.InfoContext(ctx, "Processed scan records", "records", count, "bidprentjes", len(scans))
 	return scans, nil
 }
 
+// indexPath returns the directory of the index
+func indexPath() string {
+	return filepath.Join(IndexDir, indexName)
+}
+
 // Helper function to create a new index with proper mapping
 func (s *Store) createNewIndex() error {
+	index, err := newIndex(indexPath())
+	if err != nil {
+		return err
+	}
+	s.index = index
+	return nil
+}
+
+// newIndex creates an empty index with the current mapping at path,
+// replacing any index there
+func newIndex(path string) (bleve.Index, error) {
 	// Remove existing index if it exists
-	if err := os.RemoveAll(indexPath); err != nil {
-		log.Printf("Error removing existing index: %v", err)
+	if err := os.RemoveAll(path); err != nil {
+		slog.Warn("Failed to remove existing index", "error", err)
 	}
 
 	// Create new index with proper mapping
@@ -225,7 +418,46 @@ func (s *Store) createNewIndex() error {
 			},
 		})
 	if err != nil {
-		return fmt.Errorf("failed to create analyzer: %v", err)
+		return nil, fmt.Errorf("failed to create analyzer: %v", err)
+	}
+
+	// The folded analyzer also removes diacritics and historical spellings,
+	// see foldSpelling
+	err = indexMapping.AddCustomAnalyzer("bidprentje_folded",
+		map[string]interface{}{
+			"type":      custom.Name,
+			"tokenizer": unicode.Name,
+			"token_filters": []string{
+				lowercase.Name,
+				nl.StopName,
+				spellingFoldingName,
+			},
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folded analyzer: %v", err)
+	}
+
+	// Phonetic keys are computed before indexing and only split on spaces
+	err = indexMapping.AddCustomAnalyzer("phonetic",
+		map[string]interface{}{
+			"type":      custom.Name,
+			"tokenizer": whitespace.Name,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create phonetic analyzer: %v", err)
+	}
+
+	// Sort keys keep the whole value as a single lowercased term
+	err = indexMapping.AddCustomAnalyzer("sortkey",
+		map[string]interface{}{
+			"type":      custom.Name,
+			"tokenizer": single.Name,
+			"token_filters": []string{
+				lowercase.Name,
+			},
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sort analyzer: %v", err)
 	}
 
 	// Create document mapping
@@ -246,38 +478,118 @@ func (s *Store) createNewIndex() error {
 	keywordFieldMapping.Index = true
 	keywordFieldMapping.Analyzer = "keyword"
 
+	dateFieldMapping := bleve.NewDateTimeFieldMapping()
+	dateFieldMapping.Store = true
+	dateFieldMapping.Index = true
+
+	numericFieldMapping := bleve.NewNumericFieldMapping()
+	numericFieldMapping.Store = true
+	numericFieldMapping.Index = true
+
+	// Folded copies of the text fields, indexed next to the original forms
+	foldedFieldMapping := func(field string) *mapping.FieldMapping {
+		m := bleve.NewTextFieldMapping()
+		m.Name = field + "_folded"
+		m.Store = false
+		m.Analyzer = "bidprentje_folded"
+		m.IncludeInAll = false
+		return m
+	}
+
+	phoneticFieldMapping := bleve.NewTextFieldMapping()
+	phoneticFieldMapping.Store = false
+	phoneticFieldMapping.Analyzer = "phonetic"
+	phoneticFieldMapping.IncludeInAll = false
+
+	sortFieldMapping := bleve.NewTextFieldMapping()
+	sortFieldMapping.Store = false
+	sortFieldMapping.Analyzer = "sortkey"
+	sortFieldMapping.IncludeInAll = false
+	sortFieldMapping.IncludeTermVectors = false
+
+	// Whole surnames are matched as one term; their term vectors tell
+	// which results matched on them
+	nameKeyFieldMapping := bleve.NewTextFieldMapping()
+	nameKeyFieldMapping.Store = false
+	nameKeyFieldMapping.Analyzer = "sortkey"
+	nameKeyFieldMapping.IncludeInAll = false
+
+	// Suggestion terms are only read from the term dictionary
+	suggestFieldMapping := bleve.NewKeywordFieldMapping()
+	suggestFieldMapping.Store = false
+	suggestFieldMapping.IncludeInAll = false
+	suggestFieldMapping.IncludeTermVectors = false
+	suggestFieldMapping.DocValues = false
+
+	// Facet fields are only needed for their doc values
+	facetFieldMapping := bleve.NewKeywordFieldMapping()
+	facetFieldMapping.Store = false
+	facetFieldMapping.IncludeInAll = false
+	facetFieldMapping.IncludeTermVectors = false
+
 	// Configure field mappings
 	docMapping.AddFieldMappingsAt("_id", keywordFieldMapping)
 	docMapping.AddFieldMappingsAt("id", keywordFieldMapping)
-	docMapping.AddFieldMappingsAt("voornaam", textFieldMapping)
-	docMapping.AddFieldMappingsAt("achternaam", textFieldMapping)
+	docMapping.AddFieldMappingsAt("voornaam", textFieldMapping, foldedFieldMapping("voornaam"))
+	docMapping.AddFieldMappingsAt("achternaam", textFieldMapping, foldedFieldMapping("achternaam"))
 	docMapping.AddFieldMappingsAt("tussenvoegsel", textFieldMapping)
-	docMapping.AddFieldMappingsAt("geboorteplaats", textFieldMapping)
-	docMapping.AddFieldMappingsAt("overlijdensplaats", textFieldMapping)
-	docMapping.AddFieldMappingsAt("geboortedatum", textFieldMapping)
-	docMapping.AddFieldMappingsAt("overlijdensdatum", textFieldMapping)
+	docMapping.AddFieldMappingsAt("geboorteplaats", textFieldMapping, foldedFieldMapping("geboorteplaats"))
+	docMapping.AddFieldMappingsAt("overlijdensplaats", textFieldMapping, foldedFieldMapping("overlijdensplaats"))
+	docMapping.AddFieldMappingsAt("geboortedatum", dateFieldMapping)
+	docMapping.AddFieldMappingsAt("overlijdensdatum", dateFieldMapping)
+	docMapping.AddFieldMappingsAt("geboortejaar", numericFieldMapping)
+	docMapping.AddFieldMappingsAt("overlijdensjaar", numericFieldMapping)
 	docMapping.AddFieldMappingsAt("photo", boolFieldMapping)
 	docMapping.AddFieldMappingsAt("scans", keywordFieldMapping)
+	docMapping.AddFieldMappingsAt("has_scans", boolFieldMapping)
+	docMapping.AddFieldMappingsAt("last_modified", dateFieldMapping)
+	docMapping.AddFieldMappingsAt("voornaam_phonetic", phoneticFieldMapping)
+	docMapping.AddFieldMappingsAt("achternaam_phonetic", phoneticFieldMapping)
+	docMapping.AddFieldMappingsAt("tussenvoegsel_norm", nameKeyFieldMapping)
+	docMapping.AddFieldMappingsAt("achternaam_volledig", nameKeyFieldMapping)
+	docMapping.AddFieldMappingsAt("achternaam_samengesteld", nameKeyFieldMapping)
+	docMapping.AddFieldMappingsAt("voornaam_sort", sortFieldMapping)
+	docMapping.AddFieldMappingsAt("achternaam_sort", sortFieldMapping)
+	docMapping.AddFieldMappingsAt("suggest_achternaam", suggestFieldMapping)
+	docMapping.AddFieldMappingsAt("suggest_voornaam", suggestFieldMapping)
+	docMapping.AddFieldMappingsAt("suggest_plaats", suggestFieldMapping)
+	docMapping.AddFieldMappingsAt("geboorteplaats_facet", facetFieldMapping)
+	docMapping.AddFieldMappingsAt("overlijdensplaats_facet", facetFieldMapping)
+	docMapping.AddFieldMappingsAt("geboortedecennium", facetFieldMapping)
+	docMapping.AddFieldMappingsAt("overlijdensdecennium", facetFieldMapping)
 
 	indexMapping.DefaultMapping = docMapping
 	indexMapping.DefaultAnalyzer = "bidprentje"
 
 	// Create new index
-	index, err := bleve.New(indexPath, indexMapping)
+	index, err := bleve.New(path, indexMapping)
 	if err != nil {
-		return fmt.Errorf("failed to create index: %v", err)
+		return nil, fmt.Errorf("failed to create index: %v", err)
 	}
-	s.index = index
-	return nil
+	if err := index.SetInternal(mappingVersionKey, []byte(mappingVersion)); err != nil {
+		index.Close()
+		return nil, fmt.Errorf("failed to store mapping version: %v", err)
+	}
+	return index, nil
+}
+
+// indexMappingVersion returns the mapping version of the open index, or an
+// empty string for indexes created before versions were recorded
+func (s *Store) indexMappingVersion() string {
+	version, err := s.index.GetInternal(mappingVersionKey)
+	if err != nil {
+		return ""
+	}
+	return string(version)
 }
 
 // Helper function to open existing index
 func (s *Store) openExistingIndex() error {
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+	if _, err := os.Stat(indexPath()); os.IsNotExist(err) {
 		return fmt.Errorf("index does not exist")
 	}
 
-	index, err := bleve.Open(indexPath)
+	index, err := bleve.Open(indexPath())
 	if err != nil {
 		return fmt.Errorf("failed to open index: %v", err)
 	}
@@ -287,7 +599,7 @@ func (s *Store) openExistingIndex() error {
 }
 
 // Helper function to rebuild in-memory data from index
-func (s *Store) rebuildDataFromIndex() error {
+func (s *Store) rebuildDataFromIndex(ctx context.Context) error {
 	// Create a search request that matches all documents
 	matchAll := bleve.NewMatchAllQuery()
 	searchRequest := bleve.NewSearchRequest(matchAll)
@@ -304,7 +616,7 @@ func (s *Store) rebuildDataFromIndex() error {
 	defer s.mu.Unlock()
 
 	s.data = make(map[string]*models.Bidprentje)
-	log.Println("nof hits: ", len(results.Hits))
+	slog.DebugContext(ctx, "Rebuilding data from index", "hits", len(results.Hits))
 
 	// Rebuild data from search results
 	for _, hit := range results.Hits {
@@ -320,16 +632,9 @@ func (s *Store) rebuildDataFromIndex() error {
 		}
 
 		// Parse dates
-		if geboortedatum, ok := hit.Fields["geboortedatum"].(string); ok && geboortedatum != "" {
-			if parsed, err := time.Parse("2006-01-02", geboortedatum); err == nil {
-				b.Geboortedatum = parsed
-			}
-		}
-		if overlijdensdatum, ok := hit.Fields["overlijdensdatum"].(string); ok && overlijdensdatum != "" {
-			if parsed, err := time.Parse("2006-01-02", overlijdensdatum); err == nil {
-				b.Overlijdensdatum = parsed
-			}
-		}
+		b.Geboortedatum = getDateField(hit.Fields, "geboortedatum")
+		b.Overlijdensdatum = getDateField(hit.Fields, "overlijdensdatum")
+		b.LastModified = getDateField(hit.Fields, "last_modified")
 
 		s.data[hit.ID] = b
 	}
@@ -338,21 +643,38 @@ func (s *Store) rebuildDataFromIndex() error {
 }
 
 func (s *Store) Close() error {
-	// First, ensure the index is properly closed
-	if err := s.index.Close(); err != nil {
-		log.Printf("Warning: Failed to close index: %v", err)
+	// First, ensure the index is properly closed. It is missing when the
+	// store is closed while still loading.
+	s.mu.Lock()
+	if s.index != nil {
+		if err := s.index.Close(); err != nil {
+			slog.Warn("Failed to close index", "error", err)
+		}
 	}
+	s.mu.Unlock()
 
-	// Finally close the GCS client
-	if s.gcsClient != nil {
-		if err := s.gcsClient.Close(); err != nil {
-			log.Printf("Warning: Failed to close GCS client: %v", err)
+	// Finally close the storage backend
+	if s.storage != nil {
+		if err := s.storage.Close(); err != nil {
+			slog.Warn("Failed to close storage backend", "error", err)
 		}
 	}
 
 	return nil
 }
 
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // Helper functions for tar.gz operations
 func createTarGz(src string, writer io.Writer) error {
 	gzWriter := gzip.NewWriter(writer)
@@ -487,7 +809,7 @@ func extractTarGz(reader io.Reader, dst string) error {
 	}
 
 	// Verify the index directory exists after extraction
-	if _, err := os.Stat(filepath.Join(dst, "bidprentjes.bleve")); os.IsNotExist(err) {
+	if _, err := os.Stat(filepath.Join(dst, indexName)); os.IsNotExist(err) {
 		return fmt.Errorf("index directory not found after extraction")
 	}
 
@@ -498,23 +820,28 @@ func (s *Store) Create(b *models.Bidprentje) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.data[b.ID] = b
+	return s.createLocked(b)
+}
 
-	// Create Bleve document
-	doc := BleveDocument{
-		ID:                b.ID,
-		Voornaam:          b.Voornaam,
-		Achternaam:        b.Achternaam,
-		Tussenvoegsel:     b.Tussenvoegsel,
-		Geboortedatum:     b.Geboortedatum.Format("2006-01-02"),
-		Geboortejaar:      b.Geboortedatum.Format("2006"),
-		Geboorteplaats:    b.Geboorteplaats,
-		Overlijdensdatum:  b.Overlijdensdatum.Format("2006-01-02"),
-		Overlijdensjaar:   b.Overlijdensdatum.Format("2006"),
-		Overlijdensplaats: b.Overlijdensplaats,
-		Photo:             b.Photo,
-		Scans:             b.Scans,
+// CreateIfAbsent adds b like Create, but returns ErrExists instead when a
+// bidprentje with the same ID is already stored. The check and the insert
+// happen under one lock, so concurrent creates cannot overwrite each other.
+func (s *Store) CreateIfAbsent(b *models.Bidprentje) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data[b.ID]; exists {
+		return ErrExists
 	}
+	return s.createLocked(b)
+}
+
+func (s *Store) createLocked(b *models.Bidprentje) error {
+	b.LastModified = time.Time{}
+	s.touchLocked(b, modificationTime())
+	s.data[b.ID] = b
+
+	doc := newBleveDocument(b)
 
 	return s.index.Index(b.ID, doc)
 }
@@ -531,23 +858,28 @@ func (s *Store) Update(b *models.Bidprentje) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.data[b.ID] = b
+	return s.updateLocked(b)
+}
 
-	// Create Bleve document
-	doc := BleveDocument{
-		ID:                b.ID,
-		Voornaam:          b.Voornaam,
-		Achternaam:        b.Achternaam,
-		Tussenvoegsel:     b.Tussenvoegsel,
-		Geboortedatum:     b.Geboortedatum.Format("2006-01-02"),
-		Geboortejaar:      b.Geboortedatum.Format("2006"),
-		Geboorteplaats:    b.Geboorteplaats,
-		Overlijdensdatum:  b.Overlijdensdatum.Format("2006-01-02"),
-		Overlijdensjaar:   b.Overlijdensdatum.Format("2006"),
-		Overlijdensplaats: b.Overlijdensplaats,
-		Photo:             b.Photo,
-		Scans:             b.Scans,
+// UpdateIfExists replaces the stored bidprentje with b like Update, but
+// returns ErrNotFound instead when none has its ID, so an update cannot
+// bring back a bidprentje that was deleted in the meantime
+func (s *Store) UpdateIfExists(b *models.Bidprentje) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data[b.ID]; !exists {
+		return ErrNotFound
 	}
+	return s.updateLocked(b)
+}
+
+func (s *Store) updateLocked(b *models.Bidprentje) error {
+	b.LastModified = time.Time{}
+	s.touchLocked(b, modificationTime())
+	s.data[b.ID] = b
+
+	doc := newBleveDocument(b)
 
 	return s.index.Index(b.ID, doc)
 }
@@ -560,137 +892,154 @@ func (s *Store) Delete(id string) error {
 	return s.index.Delete(id)
 }
 
-func (s *Store) List(page, pageSize int) *models.PaginatedResponse {
+// DeleteIfExists removes the bidprentje with id like Delete, but returns
+// ErrNotFound when there is none
+func (s *Store) DeleteIfExists(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.data, id)
+	return s.index.Delete(id)
+}
+
+// List returns a page of all bidprentjes ordered by sort, see
+// models.ValidSort. Relevance has no meaning without a query, so the
+// default order is by ID.
+func (s *Store) List(ctx context.Context, page, pageSize int, sort string) *models.PaginatedResponse {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Convert map to slice
-	items := make([]models.Bidprentje, 0, len(s.data))
-	for _, item := range s.data {
-		items = append(items, *item)
-	}
+	searchRequest := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+	searchRequest.Size = pageSize
+	searchRequest.From = (page - 1) * pageSize
+	searchRequest.SortByCustom(sortOrder(sort, false))
+	addFacetRequests(searchRequest)
 
-	// Calculate pagination
-	totalCount := len(items)
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if start >= totalCount {
-		start = totalCount
+	searchResults, err := s.index.Search(searchRequest)
+	if err != nil {
+		slog.ErrorContext(ctx, "List failed", "error", err)
+		return &models.PaginatedResponse{
+			Items:    []models.Bidprentje{},
+			Page:     page,
+			PageSize: pageSize,
+		}
 	}
-	if end > totalCount {
-		end = totalCount
+
+	items := make([]models.Bidprentje, 0, len(searchResults.Hits))
+	for _, hit := range searchResults.Hits {
+		if b, exists := s.data[hit.ID]; exists {
+			items = append(items, *b)
+		}
 	}
 
 	return &models.PaginatedResponse{
-		Items:      items[start:end],
-		TotalCount: totalCount,
+		Items:      items,
+		TotalCount: int(searchResults.Total),
 		Page:       page,
 		PageSize:   pageSize,
+		Facets:     facetsFromResults(searchResults.Facets),
 	}
 }
 
-func (s *Store) Search(params models.SearchParams) *models.PaginatedResponse {
+// criteriaAttr groups the fielded criteria of params that are set, by the
+// names of their query parameters, for logging searches and exports
+func criteriaAttr(params models.SearchParams) slog.Attr {
+	var attrs []any
+	addString := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	addInt := func(key string, value int) {
+		if value != 0 {
+			attrs = append(attrs, slog.Int(key, value))
+		}
+	}
+	addBool := func(key string, value *bool) {
+		if value != nil {
+			attrs = append(attrs, slog.Bool(key, *value))
+		}
+	}
+	addDate := func(key string, value time.Time) {
+		if !value.IsZero() {
+			attrs = append(attrs, slog.String(key, value.Format("2006-01-02")))
+		}
+	}
+
+	addString("voornaam", params.Voornaam)
+	addString("tussenvoegsel", params.Tussenvoegsel)
+	addString("achternaam", params.Achternaam)
+	addString("geboorteplaats", params.Geboorteplaats)
+	addString("overlijdensplaats", params.Overlijdensplaats)
+	addString("geboorteplaats_filter", params.GeboorteplaatsFilter)
+	addString("overlijdensplaats_filter", params.OverlijdensplaatsFilter)
+	addInt("geboortejaar_van", params.GeboortejaarVan)
+	addInt("geboortejaar_tot", params.GeboortejaarTot)
+	addInt("overlijdensjaar_van", params.OverlijdensjaarVan)
+	addInt("overlijdensjaar_tot", params.OverlijdensjaarTot)
+	addDate("geboortedatum_van", params.GeboortedatumVan)
+	addDate("geboortedatum_tot", params.GeboortedatumTot)
+	addDate("overlijdensdatum_van", params.OverlijdensdatumVan)
+	addDate("overlijdensdatum_tot", params.OverlijdensdatumTot)
+	addBool("has_photo", params.HasPhoto)
+	addBool("has_scans", params.HasScans)
+	return slog.Group("criteria", attrs...)
+}
+
+// Search runs a full-text and fielded query against the index. Parameters
+// without any criteria yield an empty response; an error is only returned
+// when the index itself fails.
+func (s *Store) Search(ctx context.Context, params models.SearchParams) (*models.PaginatedResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if params.Query == "" {
+	if !params.HasCriteria() {
 		return &models.PaginatedResponse{
 			Items:      []models.Bidprentje{},
 			TotalCount: 0,
 			Page:       params.Page,
 			PageSize:   params.PageSize,
-		}
+		}, nil
 	}
 
-	// Create a multi-field query that searches across all text fields
-	queryStr := strings.TrimSpace(params.Query)
-
-	// Create individual field queries
-	var queries []query.Query
-
-	if params.ExactMatch {
-		// For exact matches, only use exact match queries with high boost
-		exactFields := []struct {
-			field string
-			boost float64
-		}{
-			{"id", 2.0},
-			{"achternaam", 8.0},
-			{"voornaam", 5.0},
-			{"geboorteplaats", 3.0},
-			{"overlijdensplaats", 3.0},
-			{"overlijdensdatum", 3.0},
-			{"geboortedatum", 3.0},
-			{"overlijdensjaar", 8.0},
-			{"geboortejaar", 8.0},
-			{"scans", 10.0},
-		}
-
-		for _, f := range exactFields {
-			q := query.NewMatchQuery(queryStr)
-			q.SetField(f.field)
-			q.SetBoost(f.boost)
-			queries = append(queries, q)
-		}
-	} else {
-		// For fuzzy matches, split query into terms and create fuzzy queries for each
-		terms := strings.Fields(queryStr)
-		fields := []struct {
-			field string
-			boost float64
-		}{
-			{"id", 2.0},
-			{"achternaam", 8.0},
-			{"voornaam", 5.0},
-			{"geboorteplaats", 3.0},
-			{"overlijdensplaats", 3.0},
-			{"overlijdensdatum", 3.0},
-			{"geboortedatum", 3.0},
-			{"overlijdensjaar", 8.0},
-			{"geboortejaar", 8.0},
-			{"scans", 2.0},
-		}
-
-		// Create a fuzzy query for each term in each field
-		for _, term := range terms {
-			for _, f := range fields {
-				q := query.NewFuzzyQuery(term)
-				q.SetField(f.field)
-				q.SetBoost(f.boost)
-				q.SetFuzziness(1)
-				queries = append(queries, q)
-			}
-		}
-	}
-
-	// Combine all queries with OR
-	searchQuery := query.NewDisjunctionQuery(queries)
+	searchQuery := s.buildSearchQuery(params)
 
 	searchRequest := bleve.NewSearchRequest(searchQuery)
 	searchRequest.Size = params.PageSize
 	searchRequest.From = (params.Page - 1) * params.PageSize
-	searchRequest.SortBy([]string{"-_score"}) // Sort by score descending
-	searchRequest.Fields = []string{"*"}      // Request all stored fields
+	searchRequest.SortByCustom(sortOrder(params.Sort, true))
+	searchRequest.Fields = []string{"*"} // Request all stored fields
+	searchRequest.IncludeLocations = true
+	searchRequest.Explain = params.Explain
+	addFacetRequests(searchRequest)
 
 	startTime := time.Now()
 	searchResults, err := s.index.Search(searchRequest)
 	if err != nil {
-		log.Printf("Search error: %v", err)
-		return &models.PaginatedResponse{
-			Items:      []models.Bidprentje{},
-			TotalCount: 0,
-			Page:       params.Page,
-			PageSize:   params.PageSize,
-		}
+		slog.ErrorContext(ctx, "Search failed", "query", params.Query, "mode", params.Mode(), "error", err)
+		return nil, fmt.Errorf("search failed: %v", err)
 	}
-	log.Printf("Found %d items in %v", searchResults.Total, time.Since(startTime))
+	duration := time.Since(startTime)
+	slog.InfoContext(ctx, "Search", "query", params.Query, criteriaAttr(params), "mode", params.Mode(), "hits", searchResults.Total, "page", params.Page, "duration", duration)
+	metrics.ObserveSearch(params.Mode(), duration, searchResults.Total)
 
 	// Convert results to Bidprentje objects
 	items := make([]models.Bidprentje, 0, len(searchResults.Hits))
-	for _, hit := range searchResults.Hits {
-		if b, exists := s.data[hit.ID]; exists {
-			items = append(items, *b)
+	hits := make([]models.Hit, 0, len(searchResults.Hits))
+	for _, match := range searchResults.Hits {
+		b, exists := s.data[match.ID]
+		if !exists {
+			continue
+		}
+		hit, err := s.newHit(match, params.Explain)
+		if err != nil {
+			slog.WarnContext(ctx, "Highlighting failed", "id", match.ID, "error", err)
 		}
+		items = append(items, *b)
+		hits = append(hits, hit)
 	}
 
 	return &models.PaginatedResponse{
@@ -698,10 +1047,15 @@ func (s *Store) Search(params models.SearchParams) *models.PaginatedResponse {
 		TotalCount: int(searchResults.Total),
 		Page:       params.Page,
 		PageSize:   params.PageSize,
-	}
+		Facets:     facetsFromResults(searchResults.Facets),
+		Hits:       hits,
+	}, nil
 }
 
-// BatchCreate adds multiple bidprentjes in a single batch operation
+// BatchCreate adds multiple bidprentjes in a single batch operation. A
+// record that is stored already with the same content keeps its
+// last-modified time; other records keep a last-modified time that is set
+// already or are stamped with the current time.
 func (s *Store) BatchCreate(bidprentjes []*models.Bidprentje) error {
 	if len(bidprentjes) == 0 {
 		return nil
@@ -710,24 +1064,13 @@ func (s *Store) BatchCreate(bidprentjes []*models.Bidprentje) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	now := modificationTime()
 	batch := s.index.NewBatch()
 	for _, b := range bidprentjes {
+		s.touchLocked(b, now)
 		s.data[b.ID] = b
 
-		doc := BleveDocument{
-			ID:                b.ID,
-			Voornaam:          b.Voornaam,
-			Achternaam:        b.Achternaam,
-			Tussenvoegsel:     b.Tussenvoegsel,
-			Geboortedatum:     b.Geboortedatum.Format("2006-01-02"),
-			Geboortejaar:      b.Geboortedatum.Format("2006"),
-			Geboorteplaats:    b.Geboorteplaats,
-			Overlijdensdatum:  b.Overlijdensdatum.Format("2006-01-02"),
-			Overlijdensjaar:   b.Geboortedatum.Format("2006"),
-			Overlijdensplaats: b.Overlijdensplaats,
-			Photo:             b.Photo,
-			Scans:             b.Scans,
-		}
+		doc := newBleveDocument(b)
 
 		if err := batch.Index(b.ID, doc); err != nil {
 			return fmt.Errorf("failed to add document to batch: %v", err)
@@ -737,24 +1080,154 @@ func (s *Store) BatchCreate(bidprentjes []*models.Bidprentje) error {
 	return s.index.Batch(batch)
 }
 
+// touchLocked sets the last-modified time of b before it is stored: the
+// time of the stored record when its content is unchanged, otherwise the
+// time already set on b or else now. The caller holds s.mu.
+func (s *Store) touchLocked(b *models.Bidprentje, now time.Time) {
+	if old, exists := s.data[b.ID]; exists && !old.LastModified.IsZero() && sameContent(old, b) {
+		b.LastModified = old.LastModified
+		return
+	}
+	if b.LastModified.IsZero() {
+		b.LastModified = now
+	}
+}
+
+// sameContent reports whether a and b hold the same data, ignoring their
+// last-modified times
+func sameContent(a, b *models.Bidprentje) bool {
+	return a.ID == b.ID &&
+		a.Voornaam == b.Voornaam &&
+		a.Tussenvoegsel == b.Tussenvoegsel &&
+		a.Achternaam == b.Achternaam &&
+		a.Geboortedatum.Equal(b.Geboortedatum) &&
+		a.Geboorteplaats == b.Geboorteplaats &&
+		a.Overlijdensdatum.Equal(b.Overlijdensdatum) &&
+		a.Overlijdensplaats == b.Overlijdensplaats &&
+		a.Photo == b.Photo &&
+		slices.Equal(a.Scans, b.Scans)
+}
+
+// ImportProgress is reported by ProcessCSVUploadWithProgress after every stored chunk
+type ImportProgress struct {
+	TotalRecords    int
+	TotalChunks     int
+	ChunksDone      int
+	RecordsImported int
+	RecordsSkipped  int
+	Errors          []string
+}
+
+// maxImportErrors limits the number of error messages kept per import
+const maxImportErrors = 100
+
 // ProcessCSVUpload processes a CSV file and adds its contents to the index
-func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string) (int, error) {
+func (s *Store) ProcessCSVUpload(ctx context.Context, reader io.Reader, scanMap map[string][]string) (int, error) {
+	return s.ProcessCSVUploadWithProgress(ctx, reader, scanMap, nil)
+}
+
+// ProcessCSVUploadWithProgress processes a CSV file like ProcessCSVUpload and
+// calls progress, when not nil, after every chunk that has been stored
+func (s *Store) ProcessCSVUploadWithProgress(ctx context.Context, reader io.Reader, scanMap map[string][]string, progress func(ImportProgress)) (int, error) {
+	return s.processCSV(ctx, reader, scanMap, time.Time{}, progress)
+}
+
+// processCSV processes a CSV file like ProcessCSVUploadWithProgress. New and
+// changed records get the last-modified time modified, or the current time
+// when it is zero.
+func (s *Store) processCSV(ctx context.Context, reader io.Reader, scanMap map[string][]string, modified time.Time, progress func(ImportProgress)) (int, error) {
 	startTime := time.Now()
 	defer func() {
-		log.Printf("Total upload time: %v", time.Since(startTime))
+		slog.DebugContext(ctx, "Processed CSV upload", "duration", time.Since(startTime))
 	}()
 
 	csvReader := csv.NewReader(reader)
+	// Records with a wrong number of fields are skipped by the workers
+	csvReader.FieldsPerRecord = -1
 
 	// Read all records first
 	records, err := csvReader.ReadAll()
 	if err != nil {
-		log.Printf("Error reading CSV records: %v", err)
+		slog.WarnContext(ctx, "Failed to read CSV records", "error", err)
 		return 0, fmt.Errorf("error reading CSV: %v", err)
 	}
 
-	totalRecords := len(records)
-	log.Printf("Processing %d records", totalRecords)
+	return s.importRecords(ctx, len(records), func(i int) (*models.Bidprentje, []string) {
+		b, problems := parseCSVRecord(records[i], i+1, scanMap)
+		if b != nil {
+			b.LastModified = modified
+		}
+		return b, problems
+	}, progress)
+}
+
+// parseCSVRecord turns a CSV record into a bidprentje. Invalid dates are
+// left empty and reported; a record with the wrong number of fields is
+// skipped by returning nil.
+func parseCSVRecord(record []string, line int, scanMap map[string][]string) (*models.Bidprentje, []string) {
+	if len(record) != 9 {
+		slog.Debug("Skipping CSV record with invalid length", "line", line, "fields", len(record))
+		return nil, []string{fmt.Sprintf("line %d: invalid record length: got %d, want 9", line, len(record))}
+	}
+
+	var problems []string
+
+	// Parse dates and convert to RFC3339 format for Bleve compatibility
+	var geboortedatum, overlijdensdatum time.Time
+
+	// Handle geboortedatum
+	geboortedatumStr := strings.TrimSpace(record[4])
+	if geboortedatumStr != "" {
+		parsed, err := time.Parse("2006-01-02", geboortedatumStr)
+		if err != nil {
+			slog.Debug("Invalid geboortedatum", "line", line, "value", geboortedatumStr, "error", err)
+			problems = append(problems, fmt.Sprintf("line %d: invalid geboortedatum %q", line, geboortedatumStr))
+		} else {
+			geboortedatum = parsed
+		}
+	}
+
+	// Handle overlijdensdatum
+	overlijdensdatumStr := strings.TrimSpace(record[6])
+	if overlijdensdatumStr != "" {
+		parsed, err := time.Parse("2006-01-02", overlijdensdatumStr)
+		if err != nil {
+			slog.Debug("Invalid overlijdensdatum", "line", line, "value", overlijdensdatumStr, "error", err)
+			problems = append(problems, fmt.Sprintf("line %d: invalid overlijdensdatum %q", line, overlijdensdatumStr))
+		} else {
+			overlijdensdatum = parsed
+		}
+	}
+
+	id := normalizeID(record[0])
+	photo := strings.ToLower(strings.TrimSpace(record[8])) == "true"
+	var scans []string
+	if foundScans, ok := scanMap[id]; ok {
+		scans = foundScans
+	}
+
+	// Create record regardless of dates - they can be empty
+	return &models.Bidprentje{
+		ID:                id,
+		Voornaam:          record[1],
+		Tussenvoegsel:     record[2],
+		Achternaam:        record[3],
+		Geboortedatum:     geboortedatum,
+		Geboorteplaats:    record[5],
+		Overlijdensdatum:  overlijdensdatum,
+		Overlijdensplaats: record[7],
+		Photo:             photo,
+		Scans:             scans,
+	}, problems
+}
+
+// importRecords converts totalRecords records with parse and stores them
+// in chunks with BatchCreate. parse returns the bidprentje for record i,
+// or nil to skip it, together with any problems to report. progress, when
+// not nil, is called after every chunk that has been stored.
+func (s *Store) importRecords(ctx context.Context, totalRecords int, parse func(i int) (*models.Bidprentje, []string), progress func(ImportProgress)) (int, error) {
+	slog.InfoContext(ctx, "Importing records", "records", totalRecords)
+	startTime := time.Now()
 
 	// Process records in chunks
 	const chunkSize = 1000 // Smaller chunks for more frequent updates
@@ -771,6 +1244,8 @@ func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string)
 	type chunkResult struct {
 		chunkNum int
 		batch    []*models.Bidprentje
+		skipped  int
+		errors   []string
 		err      error
 	}
 	resultChan := make(chan chunkResult, 2) // Smaller buffer to reduce memory usage
@@ -798,59 +1273,15 @@ func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string)
 				batch = batch[:0] // Reset slice but keep capacity
 
 				// Process records in this chunk
-				chunk := records[start:end]
-				for _, record := range chunk {
-					if len(record) != 9 {
-						log.Printf("Worker %d: Invalid record length: got %d, want 9", workerId, len(record))
+				skipped := 0
+				var chunkErrors []string
+				for i := start; i < end; i++ {
+					bidprentje, problems := parse(i)
+					chunkErrors = append(chunkErrors, problems...)
+					if bidprentje == nil {
+						skipped++
 						continue
 					}
-
-					// Parse dates and convert to RFC3339 format for Bleve compatibility
-					var geboortedatum, overlijdensdatum time.Time
-
-					// Handle geboortedatum
-					geboortedatumStr := strings.TrimSpace(record[4])
-					if geboortedatumStr != "" {
-						parsed, err := time.Parse("2006-01-02", geboortedatumStr)
-						if err != nil {
-							log.Printf("Worker %d: Error parsing geboortedatum '%s': %v", workerId, geboortedatumStr, err)
-						} else {
-							geboortedatum = parsed
-						}
-					}
-
-					// Handle overlijdensdatum
-					overlijdensdatumStr := strings.TrimSpace(record[6])
-					if overlijdensdatumStr != "" {
-						parsed, err := time.Parse("2006-01-02", overlijdensdatumStr)
-						if err != nil {
-							log.Printf("Worker %d: Error parsing overlijdensdatum '%s': %v", workerId, overlijdensdatumStr, err)
-						} else {
-							overlijdensdatum = parsed
-						}
-					}
-
-					id := normalizeID(record[0])
-					photo := strings.ToLower(strings.TrimSpace(record[8])) == "true"
-					var scans []string
-					if foundScans, ok := scanMap[id]; ok {
-						scans = foundScans
-					}
-
-					// Create record regardless of dates - they can be empty
-					bidprentje := &models.Bidprentje{
-						ID:                id,
-						Voornaam:          record[1],
-						Tussenvoegsel:     record[2],
-						Achternaam:        record[3],
-						Geboortedatum:     geboortedatum,
-						Geboorteplaats:    record[5],
-						Overlijdensdatum:  overlijdensdatum,
-						Overlijdensplaats: record[7],
-						Photo:             photo,
-						Scans:             scans,
-					}
-
 					batch = append(batch, bidprentje)
 				}
 
@@ -858,11 +1289,13 @@ func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string)
 				resultChan <- chunkResult{
 					chunkNum: chunkNum,
 					batch:    batch,
+					skipped:  skipped,
+					errors:   chunkErrors,
 				}
 
 				if (chunkNum+1)%10 == 0 || chunkNum+1 == chunks {
 					progress := ((chunkNum + 1) * 100) / chunks
-					log.Printf("Worker %d progress: %d%% (%d/%d chunks)", workerId, progress, chunkNum+1, chunks)
+					slog.DebugContext(ctx, "Import progress", "worker", workerId, "percent", progress, "chunks_done", chunkNum+1, "chunks", chunks)
 				}
 			}
 		}(i)
@@ -877,21 +1310,37 @@ func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string)
 	// Process results immediately as they arrive
 	var lastError error
 	processedCount := 0
+	state := ImportProgress{
+		TotalRecords: totalRecords,
+		TotalChunks:  chunks,
+	}
 
 	for result := range resultChan {
+		state.ChunksDone++
+		state.RecordsSkipped += result.skipped
+		state.Errors = appendImportErrors(state.Errors, result.errors...)
+
 		if result.err != nil {
 			lastError = result.err
-			continue
-		}
-
-		if err := s.BatchCreate(result.batch); err != nil {
-			log.Printf("Error storing batch: %v", err)
+			state.Errors = appendImportErrors(state.Errors, result.err.Error())
+		} else if err := s.BatchCreate(result.batch); err != nil {
+			slog.ErrorContext(ctx, "Failed to store batch", "chunk", result.chunkNum+1, "error", err)
 			lastError = err
+			state.RecordsSkipped += len(result.batch)
+			state.Errors = appendImportErrors(state.Errors, fmt.Sprintf("chunk %d: %v", result.chunkNum+1, err))
+		} else {
+			state.RecordsImported += len(result.batch)
 		}
 
 		processedCount += len(result.batch)
 		// Return batch to pool
 		batchPool.Put(result.batch)
+
+		if progress != nil {
+			snapshot := state
+			snapshot.Errors = append([]string(nil), state.Errors...)
+			progress(snapshot)
+		}
 	}
 
 	if lastError != nil {
@@ -902,10 +1351,23 @@ func (s *Store) ProcessCSVUpload(reader io.Reader, scanMap map[string][]string)
 	s.hasValidIndex = true
 	s.mu.Unlock()
 
-	log.Printf("Successfully processed all %d records", totalRecords)
+	duration := time.Since(startTime)
+	metrics.ObserveImport(duration, state.RecordsImported)
+	slog.InfoContext(ctx, "Imported records", "records", state.RecordsImported, "skipped", state.RecordsSkipped, "duration", duration)
 	return totalRecords, nil
 }
 
+// appendImportErrors appends error messages up to maxImportErrors
+func appendImportErrors(errs []string, messages ...string) []string {
+	for _, msg := range messages {
+		if len(errs) >= maxImportErrors {
+			break
+		}
+		errs = append(errs, msg)
+	}
+	return errs
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -913,51 +1375,70 @@ func abs(x int) int {
 	return x
 }
 
-// HasGCPConnectivity returns true if the store has a connection to GCP
-func (s *Store) HasGCPConnectivity() bool {
-	return s.gcsClient != nil
+// HasStorageConnectivity returns true if the store has a storage backend
+func (s *Store) HasStorageConnectivity() bool {
+	return s.storage != nil
 }
 
-// downloadIndex downloads and extracts the index backup from GCP
-func (s *Store) downloadIndex(ctx context.Context) error {
-	log.Printf("Downloading index from GCP: %s", indexObject)
+// downloadIndex downloads and extracts the index backup from storage
+func (s *Store) downloadIndex(ctx context.Context) (err error) {
+	slog.InfoContext(ctx, "Downloading index from storage", "path", indexObject)
+
+	// A missing backup is not a failed restore
+	startTime := time.Now()
+	var size countingReader
+	defer func() {
+		if !errors.Is(err, cloud.ErrNotFound) {
+			metrics.ObserveBackup(metrics.OperationRestore, time.Since(startTime), size.n, err)
+		}
+	}()
 
 	// First, ensure the index directory doesn't exist (to avoid conflicts)
-	if err := os.RemoveAll(indexPath); err != nil {
-		log.Printf("Warning: Failed to remove existing index directory: %v", err)
+	if err := os.RemoveAll(indexPath()); err != nil {
+		slog.WarnContext(ctx, "Failed to remove existing index directory", "error", err)
 	}
 
 	// Create the parent directory
-	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+	if err := os.MkdirAll(IndexDir, 0755); err != nil {
 		return fmt.Errorf("failed to create index parent directory: %v", err)
 	}
 
 	// Download the index file
-	reader, err := s.gcsClient.DownloadFile(ctx, indexObject)
+	reader, err := s.storage.DownloadFile(ctx, indexObject)
 	if err != nil {
-		return fmt.Errorf("failed to download index: %v", err)
+		return fmt.Errorf("failed to download index: %w", err)
 	}
+	size.r = reader
 
-	log.Printf("Successfully downloaded index, extracting...")
+	slog.InfoContext(ctx, "Downloaded index, extracting")
 
-	// Extract the tar.gz to the parent directory of indexPath
-	if err := extractTarGz(reader, filepath.Dir(indexPath)); err != nil {
+	// Extract the tar.gz to IndexDir
+	if err := extractTarGz(&size, IndexDir); err != nil {
 		return fmt.Errorf("failed to extract index: %v", err)
 	}
 
 	// Verify the index directory exists after extraction
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+	if _, err := os.Stat(indexPath()); os.IsNotExist(err) {
 		return fmt.Errorf("index directory not found after extraction")
 	}
 
-	log.Printf("Successfully extracted index to %s", indexPath)
+	slog.InfoContext(ctx, "Extracted index", "path", indexPath(), "size", size.n, "duration", time.Since(startTime))
 	return nil
 }
 
-// uploadIndex creates a tar.gz of the index and uploads it to GCP
-func (s *Store) uploadIndex(ctx context.Context) error {
+// uploadIndex creates a tar.gz of the index and uploads it to storage
+func (s *Store) uploadIndex(ctx context.Context) (err error) {
+	startTime := time.Now()
+	var size int64
+	defer func() {
+		metrics.ObserveBackup(metrics.OperationBackup, time.Since(startTime), size, err)
+		if err == nil {
+			slog.InfoContext(ctx, "Backed up index", "path", indexObject, "size", size, "duration", time.Since(startTime))
+		}
+	}()
+
 	// First verify the index exists and is valid
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+	if _, err := os.Stat(indexPath()); os.IsNotExist(err) {
 		return fmt.Errorf("index directory does not exist")
 	}
 
@@ -969,7 +1450,7 @@ func (s *Store) uploadIndex(ctx context.Context) error {
 	defer os.Remove(tempFile.Name()) // Clean up temp file after we're done
 
 	// Create tar.gz of the index directory
-	if err := createTarGz(indexPath, tempFile); err != nil {
+	if err := createTarGz(indexPath(), tempFile); err != nil {
 		return fmt.Errorf("failed to create tar.gz: %v", err)
 	}
 
@@ -984,23 +1465,35 @@ func (s *Store) uploadIndex(ctx context.Context) error {
 		return fmt.Errorf("failed to open temporary file for upload: %v", err)
 	}
 	defer reader.Close()
+	if info, err := reader.Stat(); err == nil {
+		size = info.Size()
+	}
 
-	// Upload the tar.gz to GCP
-	if err := s.gcsClient.UploadFile(ctx, indexObject, reader); err != nil {
+	// Upload the tar.gz to storage
+	if err := s.storage.UploadFile(ctx, indexObject, reader); err != nil {
 		return fmt.Errorf("failed to upload index: %v", err)
 	}
 
 	return nil
 }
 
-// BackupIndex creates an immediate backup of the index to GCP
+// BackupIndex creates an immediate backup of the index to storage
 func (s *Store) BackupIndex(ctx context.Context) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if s.gcsClient == nil {
-		return fmt.Errorf("no GCP connectivity available")
+	if s.storage == nil {
+		return fmt.Errorf("no storage backend available")
 	}
-	return s.uploadIndex(ctx)
+	err := s.uploadIndex(ctx)
+	s.recordBackup(err)
+	return err
+}
+
+// Count returns the number of bidprentjes in the store
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data)
 }
 
 // HasValidIndex returns true if we have successfully restored or created an index with data
@@ -1043,3 +1536,18 @@ func getBoolField(fields map[string]interface{}, key string) bool {
 	}
 	return false // Return false if the field is nil or not a bool
 }
+
+// Helper function to safely get a date field, stored as RFC3339 by datetime
+// mappings and as a plain date by indexes from before mapping version 2
+func getDateField(fields map[string]interface{}, key string) time.Time {
+	val, ok := fields[key].(string)
+	if !ok || val == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, val); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
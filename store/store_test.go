@@ -2,13 +2,32 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"bidprentjes-api/a2a"
+	"bidprentjes-api/cloud"
 	"bidprentjes-api/models"
 )
 
+// date parses a YYYY-MM-DD date for test records
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatalf("invalid test date %q: %v", value, err)
+	}
+	return parsed
+}
+
 func TestStoreWithScans(t *testing.T) {
 	// Setup
 	csvData := `1,Jan,,Jansen,1900-01-01,Amsterdam,1980-01-01,Amsterdam,true
@@ -41,12 +60,12 @@ func TestStoreWithScans(t *testing.T) {
 	}
 	defer scanFile.Close()
 
-	scanMap, err := s.parseScans(scanFile)
+	scanMap, err := s.parseScans(context.Background(), scanFile)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	n, err := s.ProcessCSVUpload(strings.NewReader(csvData), scanMap)
+	n, err := s.ProcessCSVUpload(context.Background(), strings.NewReader(csvData), scanMap)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -93,7 +112,10 @@ func TestSearchWithPhotos(t *testing.T) {
 	})
 
 	// Search for Jansen
-	res := s.Search(models.SearchParams{Query: "Jansen", Page: 1, PageSize: 10})
+	res, err := s.Search(context.Background(), models.SearchParams{Query: "Jansen", Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if res.TotalCount != 1 {
 		t.Errorf("Expected 1 result, got %d", res.TotalCount)
 	}
@@ -104,3 +126,1101 @@ func TestSearchWithPhotos(t *testing.T) {
 		t.Errorf("Expected 1 scan, got %d", len(res.Items[0].Scans))
 	}
 }
+
+func TestStartImport(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	csvData := `1,Jan,,Jansen,1900-01-01,Venlo,1980-01-01,Venlo,true
+invalid,record
+`
+	job, err := s.StartImport(context.Background(), []byte(csvData), []byte("1,scan1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, changed := job.Watch()
+	for state.Status == models.ImportStatusRunning {
+		<-changed
+		state, changed = job.Watch()
+	}
+
+	if state.Status != models.ImportStatusCompleted {
+		t.Fatalf("Expected import to complete, got %s: %v", state.Status, state.Errors)
+	}
+	if state.RecordsImported != 1 || state.RecordsSkipped != 1 {
+		t.Errorf("Expected 1 imported and 1 skipped record, got %d and %d", state.RecordsImported, state.RecordsSkipped)
+	}
+	if _, ok := s.GetImport(state.ID); !ok {
+		t.Error("Expected finished import to be queryable")
+	}
+	if b, exists := s.Get("1"); !exists || len(b.Scans) != 1 {
+		t.Error("Expected record 1 with 1 scan after import")
+	}
+}
+
+func TestBackupAndRestoreWithMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := cloud.NewMemoryStorage()
+
+	s := NewStoreWithStorage(ctx, storage)
+	if err := s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Achternaam: "Jansen", Scans: []string{"scan1"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BackupIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	restored := NewStoreWithStorage(ctx, storage)
+	defer restored.Close()
+
+	if !restored.HasValidIndex() {
+		t.Fatal("Expected a valid index after restore")
+	}
+	b, exists := restored.Get("1")
+	if !exists {
+		t.Fatal("Record 1 not found after restore")
+	}
+	if b.Achternaam != "Jansen" || len(b.Scans) != 1 {
+		t.Errorf("Unexpected restored record: %+v", b)
+	}
+}
+
+func TestFieldedSearch(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Jan", Achternaam: "Jansen", Geboorteplaats: "Venlo", Geboortedatum: date(t, "1860-03-01"), Overlijdensdatum: date(t, "1918-11-02"), Photo: true},
+		{ID: "2", Voornaam: "Piet", Achternaam: "Jansen", Geboorteplaats: "Roermond", Geboortedatum: date(t, "1870-05-01"), Overlijdensdatum: date(t, "1940-01-01"), Scans: []string{"scan2"}},
+		{ID: "3", Voornaam: "Jan", Achternaam: "Pietersen", Geboorteplaats: "Venlo", Geboortedatum: date(t, "1880-01-01"), Overlijdensdatum: date(t, "1950-01-01")},
+		{ID: "4", Voornaam: "Kees", Achternaam: "Hendriks"},
+	})
+
+	yes := true
+	tests := []struct {
+		name   string
+		params models.SearchParams
+		want   []string
+	}{
+		{"surname and birth place", models.SearchParams{Achternaam: "Jansen", Geboorteplaats: "Venlo"}, []string{"1"}},
+		{"free text and first name", models.SearchParams{Query: "Venlo", Voornaam: "Jan"}, []string{"1", "3"}},
+		{"birth year range", models.SearchParams{GeboortejaarVan: 1865, GeboortejaarTot: 1885}, []string{"2", "3"}},
+		{"open death year range", models.SearchParams{OverlijdensjaarTot: 1920}, []string{"1"}},
+		{"death date range", models.SearchParams{OverlijdensdatumVan: date(t, "1918-10-01"), OverlijdensdatumTot: date(t, "1918-12-31")}, []string{"1"}},
+		{"open birth date range", models.SearchParams{GeboortedatumTot: date(t, "1875-01-01")}, []string{"1", "2"}},
+		{"free text year", models.SearchParams{Query: "1940"}, []string{"2"}},
+		{"free text date", models.SearchParams{Query: "1880-01-01"}, []string{"3"}},
+		{"has photo", models.SearchParams{HasPhoto: &yes}, []string{"1"}},
+		{"has scans", models.SearchParams{Achternaam: "Jansen", HasScans: &yes}, []string{"2"}},
+		{"exact place", models.SearchParams{Geboorteplaats: "Venl", ExactMatch: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Page, tt.params.PageSize = 1, 10
+			res, err := s.Search(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range res.Items {
+				got = append(got, item.ID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Achternaam: "Jansen", Overlijdensplaats: "Roermond", Overlijdensdatum: date(t, "1921-04-01"), Photo: true},
+		{ID: "2", Achternaam: "Jansen", Overlijdensplaats: "Roermond", Overlijdensdatum: date(t, "1929-12-31"), Scans: []string{"scan2"}},
+		{ID: "3", Achternaam: "Jansen", Overlijdensplaats: "Den Bosch", Overlijdensdatum: date(t, "1918-11-02")},
+		{ID: "4", Achternaam: "Pietersen", Overlijdensplaats: "Roermond"},
+	})
+
+	res, err := s.Search(context.Background(), models.SearchParams{Achternaam: "Jansen", Page: 1, PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Facets == nil {
+		t.Fatal("Expected facets in search response")
+	}
+
+	tests := []struct {
+		name string
+		got  []models.FacetCount
+		want []models.FacetCount
+	}{
+		{"death place", res.Facets.Overlijdensplaats, []models.FacetCount{{Value: "Roermond", Count: 2}, {Value: "Den Bosch", Count: 1}}},
+		{"death decade", res.Facets.Overlijdensdecennium, []models.FacetCount{{Value: "1910", Count: 1}, {Value: "1920", Count: 2}}},
+		{"birth decade", res.Facets.Geboortedecennium, []models.FacetCount{}},
+		{"photo", res.Facets.Photo, []models.FacetCount{{Value: "true", Count: 1}, {Value: "false", Count: 2}}},
+		{"scans", res.Facets.HasScans, []models.FacetCount{{Value: "true", Count: 1}, {Value: "false", Count: 2}}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, tt.got)
+		}
+	}
+
+	list := s.List(context.Background(), 1, 10, "")
+	if list.Facets == nil || !reflect.DeepEqual(list.Facets.Overlijdensplaats, []models.FacetCount{{Value: "Roermond", Count: 3}, {Value: "Den Bosch", Count: 1}}) {
+		t.Errorf("Expected list facets over all documents, got %+v", list.Facets)
+	}
+
+	// Place filters match the exact facet value, not similar places
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "5", Achternaam: "Smits", Geboorteplaats: "Baarlo", Overlijdensplaats: "Horst"},
+		{ID: "6", Achternaam: "Smits", Geboorteplaats: "Baarle", Overlijdensplaats: "Horst aan de Maas"},
+	})
+	filters := []struct {
+		params models.SearchParams
+		want   []string
+	}{
+		{models.SearchParams{GeboorteplaatsFilter: "Baarlo"}, []string{"5"}},
+		{models.SearchParams{OverlijdensplaatsFilter: "Horst"}, []string{"5"}},
+		{models.SearchParams{Achternaam: "Smits", OverlijdensplaatsFilter: "Horst aan de Maas"}, []string{"6"}},
+		{models.SearchParams{OverlijdensplaatsFilter: "horst"}, []string{}},
+	}
+	for _, tt := range filters {
+		tt.params.Page, tt.params.PageSize = 1, 10
+		res, err := s.Search(context.Background(), tt.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, item := range res.Items {
+			got = append(got, item.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: expected %v, got %v", tt.params, tt.want, got)
+		}
+	}
+}
+
+func TestSortOrders(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "a", Voornaam: "Piet", Achternaam: "jansen", Geboorteplaats: "Venlo", Geboortedatum: date(t, "1870-01-01")},
+		{ID: "b", Voornaam: "Anna", Achternaam: "Bakker", Geboorteplaats: "Venlo"},
+		{ID: "c", Voornaam: "Jan", Achternaam: "Jansen", Geboorteplaats: "Venlo", Geboortedatum: date(t, "1860-01-01")},
+		{ID: "d", Voornaam: "Kees", Achternaam: "Willems", Geboorteplaats: "Venlo", Geboortedatum: date(t, "1880-01-01")},
+	})
+
+	ids := func(res *models.PaginatedResponse) []string {
+		var got []string
+		for _, item := range res.Items {
+			got = append(got, item.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"a", "b", "c", "d"}},
+		{"-id", []string{"d", "c", "b", "a"}},
+		{"achternaam", []string{"b", "a", "c", "d"}},
+		{"-achternaam", []string{"d", "a", "c", "b"}},
+		{"voornaam", []string{"b", "c", "d", "a"}},
+		{"geboortedatum", []string{"c", "a", "d", "b"}},
+		{"-geboortedatum", []string{"d", "a", "c", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run("sort "+tt.sort, func(t *testing.T) {
+			if got := ids(s.List(context.Background(), 1, 10, tt.sort)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List: expected %v, got %v", tt.want, got)
+			}
+			if tt.sort == "" {
+				return
+			}
+			res, err := s.Search(context.Background(), models.SearchParams{Geboorteplaats: "Venlo", Sort: tt.sort, Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(res); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search: expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Pages of a list never overlap
+	var paged []string
+	for page := 1; page <= 4; page++ {
+		paged = append(paged, ids(s.List(context.Background(), page, 1, "achternaam"))...)
+	}
+	if want := []string{"b", "a", "c", "d"}; !reflect.DeepEqual(paged, want) {
+		t.Errorf("Expected pages %v, got %v", want, paged)
+	}
+}
+
+func TestPhoneticKey(t *testing.T) {
+	groups := [][]string{
+		{"Janssen", "Jansen", "Janssens"},
+		{"Huijbers", "Huybers", "Hoebers"},
+		{"Cornelis", "Kornelis"},
+		{"Hendrikx", "Hendricks", "Hendriks"},
+		{"Smidt", "Smit"},
+		{"Lichtenberg", "Ligtenberg"},
+		{"Meijer", "Meyer", "Meier"},
+	}
+	for _, group := range groups {
+		want := phoneticKey(group[0])
+		for _, name := range group[1:] {
+			if got := phoneticKey(name); got != want {
+				t.Errorf("Expected %s to encode like %s (%s), got %s", name, group[0], want, got)
+			}
+		}
+	}
+
+	if phoneticKey("Janssen") == phoneticKey("Hermans") {
+		t.Error("Expected different names to encode differently")
+	}
+}
+
+func TestPhoneticSearch(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Johannes", Achternaam: "Huijbers"},
+		{ID: "2", Voornaam: "Johannes", Achternaam: "Hoebers"},
+		{ID: "3", Voornaam: "Johannes", Achternaam: "Huybers"},
+		{ID: "4", Voornaam: "Johannes", Achternaam: "Hermans"},
+	})
+
+	for _, params := range []models.SearchParams{
+		{Achternaam: "Huybers", MatchMode: models.MatchPhonetic},
+		{Query: "Huybers", MatchMode: models.MatchPhonetic},
+	} {
+		params.Page, params.PageSize = 1, 10
+		res, err := s.Search(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range res.Items {
+			got = append(got, item.ID)
+		}
+		// The literal spelling ranks first for free-text searches
+		if params.Query != "" && (len(got) == 0 || got[0] != "3") {
+			t.Errorf("Expected the exact spelling first, got %v", got)
+		}
+		sort.Strings(got)
+		if want := []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v: expected %v, got %v", params, want, got)
+		}
+	}
+}
+
+func TestSurnameSearch(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Jan", Tussenvoegsel: "van den", Achternaam: "Berg"},
+		{ID: "2", Voornaam: "Piet", Achternaam: "Van den Berg"},
+		{ID: "3", Voornaam: "Kees", Achternaam: "Vandenberg"},
+		{ID: "4", Voornaam: "Anna", Tussenvoegsel: "de", Achternaam: "Vries"},
+		{ID: "5", Voornaam: "Maria", Achternaam: "Aarts"},
+	})
+
+	search := func(params models.SearchParams) []string {
+		params.Page, params.PageSize = 1, 10
+		res, err := s.Search(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range res.Items {
+			got = append(got, item.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name   string
+		params models.SearchParams
+		want   []string
+	}{
+		{"surname with particles", models.SearchParams{Achternaam: "van den Berg", MatchMode: models.MatchExact}, []string{"1", "2", "3"}},
+		{"concatenated surname", models.SearchParams{Achternaam: "Vandenberg", MatchMode: models.MatchExact}, []string{"1", "2", "3"}},
+		{"sort-name", models.SearchParams{Achternaam: "Berg, van den", MatchMode: models.MatchExact}, []string{"1", "2", "3"}},
+		{"tussenvoegsel", models.SearchParams{Tussenvoegsel: "Van den"}, []string{"1", "2"}},
+		{"tussenvoegsel and surname", models.SearchParams{Tussenvoegsel: "de", Achternaam: "Vries"}, []string{"4"}},
+		{"free text concatenated", models.SearchParams{Query: "vandenberg", MatchMode: models.MatchExact}, []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := search(tt.params)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Surnames sort by their sort-name, ignoring the particles
+	var got []string
+	for _, item := range s.List(context.Background(), 1, 10, "achternaam").Items {
+		got = append(got, item.ID)
+	}
+	if want := []string{"5", "1", "2", "3", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected sort-name order %v, got %v", want, got)
+	}
+}
+
+func TestFirstNameSynonyms(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Joannes Hubertus", Achternaam: "Janssen"},
+		{ID: "2", Voornaam: "Jan", Achternaam: "Janssen"},
+		{ID: "3", Voornaam: "Maria Catharina", Achternaam: "Janssen"},
+		{ID: "4", Voornaam: "Pieter", Achternaam: "Janssen"},
+	})
+
+	search := func(voornaam string) []string {
+		res, err := s.Search(context.Background(), models.SearchParams{Voornaam: voornaam, MatchMode: models.MatchExact, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range res.Items {
+			got = append(got, item.ID)
+		}
+		return got
+	}
+
+	// The literal name ranks above its variants
+	if got := search("Jan"); !reflect.DeepEqual(got, []string{"2", "1"}) {
+		t.Errorf("Expected [2 1], got %v", got)
+	}
+	if got := search("Huub Jan"); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("Expected [1], got %v", got)
+	}
+	if got := search("Mieke"); !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("Expected [3], got %v", got)
+	}
+
+	// Reloading picks up changes to the synonyms file
+	path := t.TempDir() + "/voornamen.txt"
+	if err := os.WriteFile(path, []byte("# test\npetrus, pieter, mieke\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LoadSynonyms(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("Mieke"); !reflect.DeepEqual(got, []string{"4"}) {
+		t.Errorf("Expected [4] after loading the file, got %v", got)
+	}
+
+	os.WriteFile(path, []byte("jan, pieter\n"), 0644)
+	synonyms, err := s.ReloadSynonyms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synonyms.Groups() != 1 {
+		t.Errorf("Expected 1 group, got %d", synonyms.Groups())
+	}
+	if got := search("Jan"); !reflect.DeepEqual(got, []string{"2", "4"}) {
+		t.Errorf("Expected [2 4] after reloading, got %v", got)
+	}
+}
+
+func TestSpellingFolding(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Hélène", Achternaam: "Müller", Geboorteplaats: "Sittard"},
+		{ID: "2", Voornaam: "Helene", Achternaam: "Mueller", Geboorteplaats: "Sittard"},
+		{ID: "3", Voornaam: "Hubert", Achternaam: "Muller", Geboorteplaats: "Sittard"},
+		{ID: "4", Voornaam: "Anna", Achternaam: "Meijer", Geboorteplaats: "Maeseyck"},
+		{ID: "5", Voornaam: "Anna", Achternaam: "Meyer", Geboorteplaats: "Maaseik"},
+	})
+
+	tests := []struct {
+		name   string
+		params models.SearchParams
+		want   []string
+	}{
+		{"umlaut fuzzy", models.SearchParams{Achternaam: "Muller"}, []string{"1", "2", "3"}},
+		{"umlaut exact", models.SearchParams{Achternaam: "Müller", MatchMode: models.MatchExact}, []string{"1"}},
+		{"accents fuzzy", models.SearchParams{Voornaam: "Helene"}, []string{"1", "2"}},
+		{"accents exact", models.SearchParams{Voornaam: "Hélène", MatchMode: models.MatchExact}, []string{"1"}},
+		{"ij and y", models.SearchParams{Query: "Meyer"}, []string{"4", "5"}},
+		{"historical place spelling", models.SearchParams{Geboorteplaats: "Maaseik"}, []string{"4", "5"}},
+	}
+
+	run := func(t *testing.T) {
+		for _, tt := range tests {
+			tt.params.Page, tt.params.PageSize = 1, 10
+			res, err := s.Search(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range res.Items {
+				got = append(got, item.ID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			}
+		}
+	}
+	run(t)
+
+	// A reindex rebuilds the same index from the in-memory data
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.indexMappingVersion() != mappingVersion {
+		t.Errorf("Expected mapping version %s after reindexing, got %q", mappingVersion, s.indexMappingVersion())
+	}
+	run(t)
+
+	// In the background the old index answers until the new one is ready
+	if _, ok := s.ReindexStatus(); ok {
+		t.Error("Expected no reindex status before StartReindex")
+	}
+	job, err := s.StartReindex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(10 * time.Second); job.Status == models.ImportStatusRunning; {
+		if time.Now().After(deadline) {
+			t.Fatal("Reindex did not finish")
+		}
+		run(t)
+		time.Sleep(10 * time.Millisecond)
+		job, _ = s.ReindexStatus()
+	}
+	if job.Status != models.ImportStatusCompleted || job.Documents != 5 {
+		t.Errorf("Expected a completed reindex of 5 documents, got %+v", job)
+	}
+	run(t)
+}
+
+func TestSuggest(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Jan", Achternaam: "Jansen", Geboorteplaats: "Venlo", Overlijdensplaats: "Venray"},
+		{ID: "2", Voornaam: "Johannes", Achternaam: "Jansen", Geboorteplaats: "Venlo", Overlijdensplaats: "Venlo"},
+		{ID: "3", Voornaam: "Jan", Achternaam: "Janssen", Geboorteplaats: "Venray"},
+		{ID: "4", Voornaam: "Maria", Tussenvoegsel: "van den", Achternaam: "Berg", Geboorteplaats: "Roermond"},
+		{ID: "5", Voornaam: "Hélène", Achternaam: "Müller", Geboorteplaats: "Sittard"},
+		{ID: "6", Voornaam: "Jan", Achternaam: "Becker", Geboorteplaats: "Tegelen"},
+		{ID: "7", Voornaam: "Jan", Achternaam: "Huijbers", Geboorteplaats: "Tegelen"},
+	})
+
+	values := func(suggestions []models.Suggestion) []string {
+		var got []string
+		for _, suggestion := range suggestions {
+			got = append(got, suggestion.Value)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		kind   string
+		want   []string
+	}{
+		{"ranked by frequency", "jan", models.SuggestAchternaam, []string{"Jansen", "Janssen"}},
+		{"first names", "jo", models.SuggestVoornaam, []string{"Johannes"}},
+		{"surname without particles", "berg", models.SuggestAchternaam, []string{"van den Berg"}},
+		{"surname with particles", "van d", models.SuggestAchternaam, []string{"van den Berg"}},
+		{"folded prefix", "Mül", models.SuggestAchternaam, []string{"Müller"}},
+		{"prefix within ck", "Bec", models.SuggestAchternaam, []string{"Becker"}},
+		{"prefix within ij", "Hui", models.SuggestAchternaam, []string{"Huijbers"}},
+		{"historical spelling", "Huyb", models.SuggestAchternaam, []string{"Huijbers"}},
+		{"historical spelling of a prefix", "bek", models.SuggestAchternaam, []string{"Becker"}},
+		{"places across fields", "ven", models.SuggestPlaats, []string{"Venlo", "Venray"}},
+		{"all kinds", "ja", "", []string{"Jan", "Jansen", "Janssen"}},
+		{"no match", "xyz", "", nil},
+	}
+
+	for _, tt := range tests {
+		suggestions, err := s.Suggest(context.Background(), tt.prefix, tt.kind, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := values(suggestions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if suggestions, err := s.Suggest(context.Background(), "ven", models.SuggestPlaats, 1); err != nil || len(suggestions) != 1 || suggestions[0].Count != 2 {
+		t.Errorf("Expected only the top place with 2 bidprentjes, got %v (%v)", suggestions, err)
+	}
+	if _, err := s.Suggest(context.Background(), "jan", "unknown", 10); err == nil {
+		t.Error("Expected an error for an unknown suggestion kind")
+	}
+}
+
+func TestSearchHighlighting(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	s.BatchCreate([]*models.Bidprentje{
+		{ID: "1", Voornaam: "Jan", Achternaam: "Janssen", Geboorteplaats: "Venlo", Overlijdensplaats: "Roermond"},
+		{ID: "2", Voornaam: "Maria", Achternaam: "Müller", Geboorteplaats: "Sittard", Overlijdensplaats: "Venlo"},
+		{ID: "3", Voornaam: "Piet", Tussenvoegsel: "van den", Achternaam: "Berg", Geboorteplaats: "Weert"},
+	})
+
+	tests := []struct {
+		name      string
+		params    models.SearchParams
+		matched   map[string][]string // matched fields by ID
+		fragments map[string]string   // achternaam or place fragment by ID
+	}{
+		{
+			"place in either field",
+			models.SearchParams{Query: "venlo"},
+			map[string][]string{"1": {"geboorteplaats"}, "2": {"overlijdensplaats"}},
+			nil,
+		},
+		{
+			"folded surname",
+			models.SearchParams{Achternaam: "Mueller"},
+			map[string][]string{"2": {"achternaam"}},
+			map[string]string{"2": "<strong>Müller</strong>"},
+		},
+		{
+			"phonetic surname",
+			models.SearchParams{Achternaam: "Jansen", MatchMode: models.MatchPhonetic},
+			map[string][]string{"1": {"achternaam"}},
+			map[string]string{"1": "<strong>Janssen</strong>"},
+		},
+		{
+			"concatenated surname",
+			models.SearchParams{Query: "vandenberg"},
+			map[string][]string{"3": {"achternaam", "tussenvoegsel"}},
+			map[string]string{"3": "<strong>Berg</strong>"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt.params.Page, tt.params.PageSize = 1, 10
+		res, err := s.Search(context.Background(), tt.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Hits) != len(res.Items) {
+			t.Fatalf("%s: expected a hit per item, got %d hits for %d items", tt.name, len(res.Hits), len(res.Items))
+		}
+
+		matched := make(map[string][]string)
+		for i, hit := range res.Hits {
+			if hit.ID != res.Items[i].ID {
+				t.Errorf("%s: hit %d is %s, item is %s", tt.name, i, hit.ID, res.Items[i].ID)
+			}
+			if hit.Explanation != nil {
+				t.Errorf("%s: expected no explanation without explain", tt.name)
+			}
+			matched[hit.ID] = hit.MatchedFields
+
+			if want, ok := tt.fragments[hit.ID]; ok {
+				if got := hit.Fragments["achternaam"]; len(got) != 1 || got[0] != want {
+					t.Errorf("%s: expected fragment %q for %s, got %v", tt.name, want, hit.ID, got)
+				}
+			}
+		}
+		if !reflect.DeepEqual(matched, tt.matched) {
+			t.Errorf("%s: expected matched fields %v, got %v", tt.name, tt.matched, matched)
+		}
+	}
+
+	res, err := s.Search(context.Background(), models.SearchParams{Query: "venlo", Explain: true, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hit := range res.Hits {
+		if len(hit.Explanation) == 0 {
+			t.Errorf("Expected a score explanation for %s", hit.ID)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	// More than one export page
+	var bidprentjes []*models.Bidprentje
+	for i := 1; i <= 2500; i++ {
+		place := "Venlo"
+		if i%5 == 0 {
+			place = "Sevenum"
+		}
+		bidprentjes = append(bidprentjes, &models.Bidprentje{
+			ID:                fmt.Sprintf("%d", i),
+			Voornaam:          "Jan",
+			Achternaam:        fmt.Sprintf("Jansen%d", i%7),
+			Overlijdensplaats: place,
+		})
+	}
+	if err := s.BatchCreate(bidprentjes); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params models.SearchParams
+		want   int
+	}{
+		{"everything", models.SearchParams{}, 2500},
+		{"by relevance", models.SearchParams{Overlijdensplaats: "Sevenum"}, 500},
+		{"by relevance, more than a page", models.SearchParams{Query: "venlo jan"}, 2500},
+		{"sorted", models.SearchParams{Query: "jan", Sort: "-achternaam"}, 2500},
+	}
+	for _, tt := range tests {
+		seen := make(map[string]bool)
+		err := s.Export(context.Background(), tt.params, 0, func(b *models.Bidprentje) error {
+			if seen[b.ID] {
+				t.Errorf("%s: %s exported twice", tt.name, b.ID)
+			}
+			seen[b.ID] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(seen) != tt.want {
+			t.Errorf("%s: expected %d records, got %d", tt.name, tt.want, len(seen))
+		}
+	}
+
+	visited := 0
+	err := s.Export(context.Background(), models.SearchParams{Overlijdensplaats: "Sevenum"}, 499, func(b *models.Bidprentje) error {
+		visited++
+		return nil
+	})
+	if !errors.Is(err, ErrExportTooLarge) || visited != 0 {
+		t.Errorf("Expected ErrExportTooLarge before exporting, got %v after %d records", err, visited)
+	}
+
+	// A cancelled export stops after the page being written
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	visited = 0
+	err = s.Export(ctx, models.SearchParams{}, 0, func(b *models.Bidprentje) error {
+		visited++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || visited != exportPageSize {
+		t.Errorf("Expected the export to stop after %d records, got %v after %d records", exportPageSize, err, visited)
+	}
+}
+
+func TestA2AImport(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	original := &models.Bidprentje{
+		ID:                "a2a-1",
+		Voornaam:          "Petrus",
+		Tussenvoegsel:     "van der",
+		Achternaam:        "Velden",
+		Geboortedatum:     time.Date(1880, 3, 4, 0, 0, 0, 0, time.UTC),
+		Geboorteplaats:    "Venlo",
+		Overlijdensdatum:  time.Date(1950, 11, 2, 0, 0, 0, 0, time.UTC),
+		Overlijdensplaats: "Tegelen",
+		Photo:             true,
+		Scans:             []string{"scan1a", "scan1b"},
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<A2ACollection>\n")
+	err := a2a.Encode(&buf, a2a.FromBidprentje(original, a2a.Options{
+		ScanURLs: func(b *models.Bidprentje) []string {
+			var urls []string
+			for _, scan := range b.Scans {
+				urls = append(urls, "https://cdn.example.org/"+scan+".jpg")
+			}
+			return urls
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A document with an incomplete death date and one without an ID
+	buf.WriteString(`
+<A2A xmlns="http://Mindbus.nl/A2A">
+  <Person pid="P"><PersonName><PersonNameFirstName>Maria</PersonNameFirstName><PersonNameLastName>Peeters</PersonNameLastName></PersonName></Person>
+  <Event eid="E"><EventType>Overlijden</EventType><EventDate><Year>1944</Year></EventDate></Event>
+  <RelationEP><PersonKeyRef>P</PersonKeyRef><EventKeyRef>E</EventKeyRef><RelationType>Overledene</RelationType></RelationEP>
+  <Source><SourceType>Bidprentje</SourceType><SourceReference><DocumentNumber>a2a-2</DocumentNumber></SourceReference></Source>
+</A2A>
+<A2A xmlns="http://Mindbus.nl/A2A">
+  <Person pid="P"><PersonName><PersonNameLastName>Zonder</PersonNameLastName></PersonName></Person>
+  <Source><SourceType>Bidprentje</SourceType></Source>
+</A2A>
+</A2ACollection>
+`)
+
+	n, err := s.ProcessA2AUpload(context.Background(), strings.NewReader(buf.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 processed documents, got %d", n)
+	}
+	if result, _ := s.Search(context.Background(), models.SearchParams{Query: "zonder", Page: 1, PageSize: 10}); result.TotalCount != 0 {
+		t.Error("Expected the document without an ID to be skipped")
+	}
+
+	got, exists := s.Get("a2a-1")
+	if !exists {
+		t.Fatal("Expected a2a-1 to be imported")
+	}
+	if got.LastModified.IsZero() {
+		t.Error("Expected the import to set the last-modified time")
+	}
+	original.LastModified = got.LastModified
+	if !reflect.DeepEqual(got, original) {
+		t.Errorf("Expected %+v after a round trip, got %+v", original, got)
+	}
+
+	got, exists = s.Get("a2a-2")
+	if !exists {
+		t.Fatal("Expected a2a-2 to be imported despite its incomplete date")
+	}
+	if got.Achternaam != "Peeters" || !got.Overlijdensdatum.IsZero() {
+		t.Errorf("Expected Peeters without a death date, got %+v", got)
+	}
+}
+
+func TestHarvest(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	var bidprentjes []*models.Bidprentje
+	for i := 1; i <= 25; i++ {
+		bidprentjes = append(bidprentjes, &models.Bidprentje{ID: fmt.Sprintf("%02d", i), Achternaam: "Jansen"})
+	}
+	if err := s.BatchCreate(bidprentjes); err != nil {
+		t.Fatal(err)
+	}
+	created := bidprentjes[0].LastModified
+	if created.IsZero() {
+		t.Fatal("Expected BatchCreate to set the last-modified time")
+	}
+	if earliest := s.EarliestModified(); !earliest.Equal(created) {
+		t.Errorf("Expected earliest modification %v, got %v", created, earliest)
+	}
+
+	// Pages follow each other without gaps or repeats
+	var ids []string
+	after := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Expected harvest to end after 3 pages")
+		}
+		page, err := s.Harvest(time.Time{}, time.Time{}, after, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 25 {
+			t.Errorf("Expected total 25, got %d", page.Total)
+		}
+		for _, b := range page.Items {
+			ids = append(ids, b.ID)
+		}
+		if page.Last == "" {
+			break
+		}
+		after = page.Last
+	}
+	if len(ids) != 25 || !sort.StringsAreSorted(ids) || ids[0] != "01" || ids[24] != "25" {
+		t.Errorf("Expected IDs 01 to 25 in order, got %v", ids)
+	}
+
+	// Only records modified within the range are selected
+	later := created.Add(time.Hour)
+	b, _ := s.Get("07")
+	updated := *b
+	updated.Voornaam = "Piet"
+	if err := s.Update(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.LastModified.IsZero() {
+		t.Error("Expected Update to set the last-modified time")
+	}
+	// Pretend the update happened an hour later
+	updated.LastModified = later
+	s.data[updated.ID] = &updated
+	if err := s.index.Index(updated.ID, newBleveDocument(&updated)); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := s.Harvest(later, time.Time{}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ID != "07" || page.Last != "" {
+		t.Errorf("Expected only 07 from %v, got %+v", later, page)
+	}
+	page, err = s.Harvest(time.Time{}, later.Add(-time.Second), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 24 {
+		t.Errorf("Expected 24 records until before %v, got %d", later, page.Total)
+	}
+
+	// Dates the index cannot hold select nothing or everything
+	ranges := []struct {
+		from, until time.Time
+		want        int
+	}{
+		{date(t, "2999-01-01"), time.Time{}, 0},
+		{time.Time{}, date(t, "1000-01-01"), 0},
+		{date(t, "1000-01-01"), date(t, "2999-01-01"), 25},
+	}
+	for _, r := range ranges {
+		page, err := s.Harvest(r.from, r.until, "", 10)
+		if err != nil {
+			t.Errorf("from %v until %v: %v", r.from, r.until, err)
+		} else if page.Total != r.want {
+			t.Errorf("from %v until %v: expected %d records, got %d", r.from, r.until, r.want, page.Total)
+		}
+	}
+}
+
+func TestUnchangedRecordsKeepLastModified(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	fileTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	csvData := `1,Jan,,Jansen,1900-01-01,Venlo,1980-01-01,Venlo,true
+2,Piet,,Peeters,1910-01-01,Horst,1990-01-01,Horst,false
+`
+	if _, err := s.processCSV(context.Background(), strings.NewReader(csvData), nil, fileTime, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if b, _ := s.Get(id); !b.LastModified.Equal(fileTime) {
+			t.Errorf("Expected %s to date from the file at %v, got %v", id, fileTime, b.LastModified)
+		}
+	}
+
+	// Importing the same file again only stamps the changed record
+	csvData = strings.Replace(csvData, "Peeters", "Pieters", 1)
+	if _, err := s.ProcessCSVUpload(context.Background(), strings.NewReader(csvData), nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.Get("1"); !b.LastModified.Equal(fileTime) {
+		t.Errorf("Expected unchanged record 1 to keep %v, got %v", fileTime, b.LastModified)
+	}
+	if b, _ := s.Get("2"); !b.LastModified.After(fileTime) {
+		t.Errorf("Expected changed record 2 to be modified after %v, got %v", fileTime, b.LastModified)
+	}
+
+	// So does an update
+	b, _ := s.Get("1")
+	unchanged := *b
+	if err := s.Update(&unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if !unchanged.LastModified.Equal(fileTime) {
+		t.Errorf("Expected an update without changes to keep %v, got %v", fileTime, unchanged.LastModified)
+	}
+	if page, err := s.Harvest(fileTime.Add(time.Second), time.Time{}, "", 10); err != nil || page.Total != 1 {
+		t.Errorf("Expected only record 2 to be harvested as modified, got %+v, %v", page, err)
+	}
+}
+
+func TestReindexStampsCopies(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	if err := s.BatchCreate([]*models.Bidprentje{{ID: "1", Achternaam: "Jansen"}}); err != nil {
+		t.Fatal(err)
+	}
+	// A record from before last-modified times were kept
+	old, _ := s.Get("1")
+	old.LastModified = time.Time{}
+
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !old.LastModified.IsZero() {
+		t.Error("Expected the record held by a reader to be left alone")
+	}
+	stamped, _ := s.Get("1")
+	if stamped == old || stamped.LastModified.IsZero() {
+		t.Errorf("Expected a stamped copy of the record, got %+v", stamped)
+	}
+}
+
+func TestSitemapPage(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	var bidprentjes []*models.Bidprentje
+	for i := 1; i <= 5; i++ {
+		bidprentjes = append(bidprentjes, &models.Bidprentje{ID: fmt.Sprintf("%d", i), Achternaam: "Jansen"})
+	}
+	if err := s.BatchCreate(bidprentjes); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, total, err := s.SitemapPage(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 {
+		t.Errorf("Expected total 5, got %d", total)
+	}
+	if len(entries) != 2 || entries[0].ID != "3" || entries[1].ID != "4" {
+		t.Errorf("Expected 3 and 4 on the second page, got %+v", entries)
+	}
+	if entries[0].LastModified.IsZero() {
+		t.Error("Expected entries with a last-modified time")
+	}
+
+	entries, _, err = s.SitemapPage(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries after the last page, got %+v", entries)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	s := NewStoreWithStorage(ctx, cloud.NewMemoryStorage())
+	defer s.Close()
+
+	status := s.Status(ctx)
+	if status.IndexSource != SourceEmpty || status.ValidIndex {
+		t.Errorf("Expected an empty fallback index, got %+v", status)
+	}
+	if status.MappingVersion != mappingVersion {
+		t.Errorf("Expected mapping version %s, got %q", mappingVersion, status.MappingVersion)
+	}
+	if !status.Storage.Configured || !status.Storage.Reachable {
+		t.Errorf("Expected reachable storage, got %+v", status.Storage)
+	}
+	if status.LastBackup != nil {
+		t.Errorf("Expected no backup yet, got %v", status.LastBackup)
+	}
+	if err := s.CheckIndex(); err != nil {
+		t.Errorf("Expected a readable index, got %v", err)
+	}
+
+	if err := s.BatchCreate([]*models.Bidprentje{{ID: "1", Achternaam: "Jansen"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BackupIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	status = s.Status(ctx)
+	if status.Documents != 1 || status.LastBackup == nil || status.LastBackupError != "" {
+		t.Errorf("Expected one document and a backup, got %+v", status)
+	}
+}
+
+func TestStartStore(t *testing.T) {
+	s := StartStore(context.Background(), "mem://")
+	defer s.Close()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !s.Ready() {
+		if state, err := s.State(); state == StateFailed {
+			t.Fatalf("Expected the store to load, got %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the store to be ready within 10 seconds")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status := s.Status(context.Background()); status.State != StateReady || status.IndexSource != SourceEmpty {
+		t.Errorf("Expected a ready empty store, got %+v", status)
+	}
+	if err := s.Create(&models.Bidprentje{ID: "1", Achternaam: "Jansen"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Count() != 1 {
+		t.Errorf("Expected 1 bidprentje, got %d", s.Count())
+	}
+}
+
+func TestCreateIfAbsent(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	// Of many concurrent creates with the same ID exactly one succeeds
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.CreateIfAbsent(&models.Bidprentje{ID: "1", Achternaam: fmt.Sprintf("Jansen%d", i)})
+			if err == nil {
+				created.Add(1)
+			} else if !errors.Is(err, ErrExists) {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if created.Load() != 1 || s.Count() != 1 {
+		t.Errorf("Expected one create to succeed, got %d creates and %d bidprentjes", created.Load(), s.Count())
+	}
+}
+
+func TestUpdateAndDeleteIfExists(t *testing.T) {
+	s := NewStore(context.Background(), "")
+	defer s.Close()
+
+	if err := s.CreateIfAbsent(&models.Bidprentje{ID: "1", Achternaam: "Jansen"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateIfExists(&models.Bidprentje{ID: "1", Achternaam: "Janssen"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.Get("1"); b.Achternaam != "Janssen" {
+		t.Errorf("Expected the update to be stored, got %+v", b)
+	}
+
+	if err := s.DeleteIfExists("1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteIfExists("1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	// An update after the delete does not bring the bidprentje back
+	if err := s.UpdateIfExists(&models.Bidprentje{ID: "1", Achternaam: "Jansen"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating a deleted bidprentje, got %v", err)
+	}
+	if _, exists := s.Get("1"); exists || s.Count() != 0 {
+		t.Error("Expected the deleted bidprentje to stay deleted")
+	}
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"bidprentjes-api/models"
+)
+
+// suggestSeparator splits the folded lookup key of a suggestion term from
+// the value as written, e.g. "mueller\x1fMüller"
+const suggestSeparator = "\x1f"
+
+// suggestFields maps the suggestion kinds to their index fields
+var suggestFields = map[string]string{
+	models.SuggestAchternaam: "suggest_achternaam",
+	models.SuggestVoornaam:   "suggest_voornaam",
+	models.SuggestPlaats:     "suggest_plaats",
+}
+
+// suggestTerm builds the suggestion terms that are found by the folded
+// prefixes of key and show value. Key is folded without and with the
+// historical spellings: "Huijbers" is found by "hui" while it is being
+// typed, and by "huyb" as well.
+func suggestTerm(key, value string) []string {
+	keys := suggestKeys(key)
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, key+suggestSeparator+value)
+	}
+	return terms
+}
+
+// suggestKeys returns the distinct foldings of s that suggestion terms are
+// looked up by
+func suggestKeys(s string) []string {
+	diacritics, spelling := foldDiacritics(s), foldSpelling(s)
+	if diacritics == spelling {
+		return []string{diacritics}
+	}
+	return []string{diacritics, spelling}
+}
+
+// suggestTerms returns the unique suggestion terms for the given values
+func suggestTerms(values ...string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, value := range values {
+		value = strings.Join(strings.Fields(value), " ")
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		terms = append(terms, suggestTerm(value, value)...)
+	}
+	return terms
+}
+
+// surnameSuggestTerms lets "van den Berg" be found by typing "van" as well
+// as "berg"
+func surnameSuggestTerms(name surname, tussenvoegsel, achternaam string) []string {
+	display := strings.Join(strings.Fields(tussenvoegsel+" "+achternaam), " ")
+	if display == "" {
+		return nil
+	}
+	terms := suggestTerm(display, display)
+	if name.Particles != "" && name.Name != "" {
+		terms = append(terms, suggestTerm(name.Name, display)...)
+	}
+	return terms
+}
+
+// Suggest returns up to limit completions of prefix for the given kind, or
+// for all kinds when kind is empty. Completions are read from the term
+// dictionary and ranked by the number of bidprentjes they occur in.
+func (s *Store) Suggest(ctx context.Context, prefix, kind string, limit int) ([]models.Suggestion, error) {
+	startTime := time.Now()
+	keys := suggestKeys(strings.Join(strings.Fields(prefix), " "))
+	if keys[0] == "" {
+		return []models.Suggestion{}, nil
+	}
+
+	kinds := []string{models.SuggestAchternaam, models.SuggestVoornaam, models.SuggestPlaats}
+	if kind != "" {
+		kinds = []string{kind}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	suggestions := []models.Suggestion{}
+	for _, kind := range kinds {
+		field, ok := suggestFields[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown suggestion kind %q", kind)
+		}
+
+		// A value found through several keys counts its documents once
+		counts := make(map[string]int)
+		for _, key := range keys {
+			if err := s.countSuggestions(field, key, counts); err != nil {
+				return nil, err
+			}
+		}
+
+		for value, count := range counts {
+			suggestions = append(suggestions, models.Suggestion{Value: value, Field: kind, Count: count})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Value < suggestions[j].Value
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	// Suggestions are requested while typing, so they are only logged for
+	// debugging
+	slog.DebugContext(ctx, "Suggest", "query", prefix, "field", kind, "suggestions", len(suggestions), "duration", time.Since(startTime))
+	return suggestions, nil
+}
+
+// countSuggestions adds the values of the terms of field that start with
+// key to counts, with the number of bidprentjes they occur in
+func (s *Store) countSuggestions(field, key string, counts map[string]int) error {
+	dict, err := s.index.FieldDictPrefix(field, []byte(key))
+	if err != nil {
+		return fmt.Errorf("failed to read term dictionary: %v", err)
+	}
+	defer dict.Close()
+
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read term dictionary: %v", err)
+		}
+		if entry == nil {
+			return nil
+		}
+		_, value, found := strings.Cut(entry.Term, suggestSeparator)
+		if !found {
+			continue
+		}
+		counts[value] = max(counts[value], int(entry.Count))
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// surnameParticles are the Dutch, Flemish and German words that precede a
+// surname and are ignored when sorting, like "van", "de" and "'t"
+var surnameParticles = map[string]bool{
+	"van": true, "de": true, "der": true, "den": true, "het": true, "'t": true,
+	"te": true, "ter": true, "ten": true, "in": true, "'s": true, "op": true,
+	"d'": true, "l'": true, "la": true, "le": true, "les": true, "du": true,
+	"des": true, "da": true, "di": true, "uit": true, "uijt": true, "aan": true,
+	"bij": true, "onder": true, "over": true, "voor": true, "vander": true,
+	"vanden": true, "vande": true, "von": true, "zu": true, "vom": true,
+	"zum": true, "zur": true, "auf": true,
+}
+
+// surname is a surname split into its particles and the name itself
+type surname struct {
+	Particles string // e.g. "van den", lowercased
+	Name      string // e.g. "Berg"
+}
+
+// parseSurname splits a tussenvoegsel and achternaam into the particles and
+// the surname proper. Particles are also recognised at the start of the
+// achternaam, as Flemish records write "Van den Berg" without a separate
+// tussenvoegsel, and in the "Berg, van den" sort-name form.
+func parseSurname(tussenvoegsel, achternaam string) surname {
+	particles := strings.Fields(strings.ToLower(tussenvoegsel))
+
+	name := strings.TrimSpace(achternaam)
+	if before, after, found := strings.Cut(name, ","); found {
+		name = strings.TrimSpace(before)
+		particles = append(particles, strings.Fields(strings.ToLower(after))...)
+	}
+
+	words := strings.Fields(name)
+	for len(words) > 1 && surnameParticles[strings.ToLower(words[0])] {
+		particles = append(particles, strings.ToLower(words[0]))
+		words = words[1:]
+	}
+
+	return surname{
+		Particles: strings.Join(particles, " "),
+		Name:      strings.Join(words, " "),
+	}
+}
+
+// SortName returns the name as sorted in Dutch registers, "Berg, van den"
+func (n surname) SortName() string {
+	if n.Particles == "" {
+		return n.Name
+	}
+	return n.Name + ", " + n.Particles
+}
+
+// FullName returns the lowercased name as written, "van den berg"
+func (n surname) FullName() string {
+	return strings.TrimSpace(strings.ToLower(n.Particles + " " + n.Name))
+}
+
+// Concatenated returns the full name without spaces or punctuation,
+// "vandenberg", matching how the name is often written in one word
+func (n surname) Concatenated() string {
+	return concatenateName(n.Particles + n.Name)
+}
+
+// concatenateName lowercases name and removes everything but letters and
+// digits. Diacritics are kept, so exact searches still honour them.
+func concatenateName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.ToLower(name))
+}
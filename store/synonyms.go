@@ -0,0 +1,139 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultSynonyms is the starter list of Dutch, Limburgish and German
+// first-name variants, used when no synonyms file is configured
+//
+//go:embed voornamen.txt
+var defaultSynonyms []byte
+
+// Synonyms maps first names to their known variants, e.g. Jan to Johannes
+// and Joannes
+type Synonyms struct {
+	variants map[string][]string
+	groups   int
+}
+
+// ParseSynonyms reads one group of equivalent names per line, separated by
+// commas. Empty lines and lines starting with # are ignored.
+func ParseSynonyms(reader io.Reader) (*Synonyms, error) {
+	sets := make(map[string]map[string]bool)
+	groups := 0
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var names []string
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) < 2 {
+			continue
+		}
+		groups++
+
+		for _, name := range names {
+			if sets[name] == nil {
+				sets[name] = make(map[string]bool)
+			}
+			for _, variant := range names {
+				if variant != name {
+					sets[name][variant] = true
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read synonyms: %v", err)
+	}
+
+	variants := make(map[string][]string, len(sets))
+	for name, set := range sets {
+		for variant := range set {
+			variants[name] = append(variants[name], variant)
+		}
+		sort.Strings(variants[name])
+	}
+
+	return &Synonyms{variants: variants, groups: groups}, nil
+}
+
+// Variants returns the known variants of name, without name itself
+func (sy *Synonyms) Variants(name string) []string {
+	if sy == nil {
+		return nil
+	}
+	return sy.variants[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// Groups returns the number of name groups that were loaded
+func (sy *Synonyms) Groups() int {
+	if sy == nil {
+		return 0
+	}
+	return sy.groups
+}
+
+// LoadSynonyms replaces the first-name synonyms with those in the file at
+// path, or with the built-in list when path is empty. Later calls to
+// ReloadSynonyms read the same file again, also when this one failed.
+func (s *Store) LoadSynonyms(path string) (*Synonyms, error) {
+	s.synonymsMu.Lock()
+	s.synonymsPath = path
+	s.synonymsMu.Unlock()
+
+	content := defaultSynonyms
+	if path != "" {
+		var err error
+		content, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read synonyms file: %v", err)
+		}
+	}
+
+	synonyms, err := ParseSynonyms(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	s.synonymsMu.Lock()
+	s.synonyms = synonyms
+	s.synonymsMu.Unlock()
+
+	slog.Info("Loaded first-name synonyms", "path", path, "groups", synonyms.Groups())
+	return synonyms, nil
+}
+
+// ReloadSynonyms reads the synonyms file given to LoadSynonyms again. The
+// current synonyms are kept when the file cannot be read or parsed.
+func (s *Store) ReloadSynonyms() (*Synonyms, error) {
+	s.synonymsMu.RLock()
+	path := s.synonymsPath
+	s.synonymsMu.RUnlock()
+
+	return s.LoadSynonyms(path)
+}
+
+// currentSynonyms returns the synonyms in use
+func (s *Store) currentSynonyms() *Synonyms {
+	s.synonymsMu.RLock()
+	defer s.synonymsMu.RUnlock()
+	return s.synonyms
+}
@@ -57,6 +57,62 @@ type Translations struct {
 	Of                   string
 	ID                   string
 	ExactMatch           string
+	Save                 string
+	SaveSuccess          string
+	SaveError            string
+	ScansHelp            string
+	IDRequired           string
+	InvalidDate          string
+	DeathBeforeBirth     string
+	AlreadyExists        string
+	NotFound             string
+	SelectScansFile      string
+	RecordsSkipped       string
+	ImportErrors         string
+	RecentImports        string
+	Status               string
+	Started              string
+	AdvancedSearch       string
+	BirthYear            string
+	DeathYear            string
+	From                 string
+	To                   string
+	Any                  string
+	HasScans             string
+	RefineResults        string
+	BirthDecade          string
+	DeathDecade          string
+	SortBy               string
+	Relevance            string
+	Ascending            string
+	Descending           string
+	MatchMode            string
+	FuzzyMatch           string
+	PhoneticMatch        string
+	MatchedOn            string
+	Score                string
+	Export               string
+	ExportCSV            string
+	ExportCSVScanLinks   string
+	A2AFormat            string
+	BackToSearch         string
+	Front                string
+	Reverse              string
+	PreviousScan         string
+	NextScan             string
+	ZoomIn               string
+	ZoomOut              string
+	ResetZoom            string
+	NoScans              string
+	Citation             string
+	CopyCitation         string
+	Copied               string
+	Accessed             string
+	Permalink            string
+	Loading              string
+	LoadingHelp          string
+	LoadFailed           string
+	LoadFailedHelp       string
 }
 
 var translations = map[string]Translations{
@@ -105,6 +161,62 @@ var translations = map[string]Translations{
 		Of:                   "of",
 		ID:                   "ID",
 		ExactMatch:           "Exact matches only",
+		Save:                 "Save",
+		SaveSuccess:          "Successfully saved bidprentje",
+		SaveError:            "Failed to save bidprentje",
+		ScansHelp:            "One scan ID per line, without the .jpg extension",
+		IDRequired:           "ID is required",
+		InvalidDate:          "Dates should be in YYYY-MM-DD format",
+		DeathBeforeBirth:     "Death date cannot be before birth date",
+		AlreadyExists:        "A bidprentje with this ID already exists",
+		NotFound:             "Bidprentje not found",
+		SelectScansFile:      "Select scans CSV file (optional)",
+		RecordsSkipped:       "records skipped",
+		ImportErrors:         "Errors",
+		RecentImports:        "Recent imports",
+		Status:               "Status",
+		Started:              "Started",
+		AdvancedSearch:       "Advanced search",
+		BirthYear:            "Birth year",
+		DeathYear:            "Death year",
+		From:                 "From",
+		To:                   "To",
+		Any:                  "Any",
+		HasScans:             "Scans available",
+		RefineResults:        "Refine results",
+		BirthDecade:          "Birth decade",
+		DeathDecade:          "Death decade",
+		SortBy:               "Sort by",
+		Relevance:            "Relevance",
+		Ascending:            "ascending",
+		Descending:           "descending",
+		MatchMode:            "Matching",
+		FuzzyMatch:           "Similar spelling",
+		PhoneticMatch:        "Sounds like",
+		MatchedOn:            "Matched on",
+		Score:                "Score",
+		Export:               "Export",
+		ExportCSV:            "CSV (for re-import)",
+		ExportCSVScanLinks:   "CSV with scan links (not for re-import)",
+		A2AFormat:            "A2A XML files with one or more records are also accepted; scans are then taken from the documents unless a scans CSV is given.",
+		BackToSearch:         "Back to search",
+		Front:                "Front",
+		Reverse:              "Back",
+		PreviousScan:         "Previous scan",
+		NextScan:             "Next scan",
+		ZoomIn:               "Zoom in",
+		ZoomOut:              "Zoom out",
+		ResetZoom:            "Reset zoom",
+		NoScans:              "No scans available",
+		Citation:             "Cite this bidprentje",
+		CopyCitation:         "Copy",
+		Copied:               "Copied",
+		Accessed:             "accessed",
+		Permalink:            "Permanent link",
+		Loading:              "The archive is loading",
+		LoadingHelp:          "This can take a few minutes after a restart. This page reloads by itself.",
+		LoadFailed:           "The archive could not be loaded",
+		LoadFailedHelp:       "Please try again later or contact the administrator.",
 	},
 	"nl": {
 		Search:               "Bidprentjes zoeken",
@@ -151,6 +263,62 @@ var translations = map[string]Translations{
 		Of:                   "van",
 		ID:                   "ID",
 		ExactMatch:           "Alleen exacte overeenkomsten",
+		Save:                 "Opslaan",
+		SaveSuccess:          "Bidprentje succesvol opgeslagen",
+		SaveError:            "Fout bij opslaan bidprentje",
+		ScansHelp:            "Eén scan-ID per regel, zonder de .jpg extensie",
+		IDRequired:           "ID is verplicht",
+		InvalidDate:          "Datums moeten in JJJJ-MM-DD formaat zijn",
+		DeathBeforeBirth:     "Overlijdensdatum kan niet voor de geboortedatum liggen",
+		AlreadyExists:        "Er bestaat al een bidprentje met dit ID",
+		NotFound:             "Bidprentje niet gevonden",
+		SelectScansFile:      "Selecteer scans CSV bestand (optioneel)",
+		RecordsSkipped:       "records overgeslagen",
+		ImportErrors:         "Fouten",
+		RecentImports:        "Recente imports",
+		Status:               "Status",
+		Started:              "Gestart",
+		AdvancedSearch:       "Uitgebreid zoeken",
+		BirthYear:            "Geboortejaar",
+		DeathYear:            "Overlijdensjaar",
+		From:                 "Van",
+		To:                   "Tot",
+		Any:                  "Maakt niet uit",
+		HasScans:             "Scans beschikbaar",
+		RefineResults:        "Resultaten verfijnen",
+		BirthDecade:          "Geboortedecennium",
+		DeathDecade:          "Overlijdensdecennium",
+		SortBy:               "Sorteren op",
+		Relevance:            "Relevantie",
+		Ascending:            "oplopend",
+		Descending:           "aflopend",
+		MatchMode:            "Zoekwijze",
+		FuzzyMatch:           "Vergelijkbare spelling",
+		PhoneticMatch:        "Klinkt als",
+		MatchedOn:            "Gevonden op",
+		Score:                "Score",
+		Export:               "Exporteren",
+		ExportCSV:            "CSV (om opnieuw te importeren)",
+		ExportCSVScanLinks:   "CSV met scanlinks (niet om te importeren)",
+		A2AFormat:            "A2A XML-bestanden met een of meer records worden ook geaccepteerd; de scans komen dan uit de documenten, tenzij er een scans-CSV is opgegeven.",
+		BackToSearch:         "Terug naar zoeken",
+		Front:                "Voorkant",
+		Reverse:              "Achterkant",
+		PreviousScan:         "Vorige scan",
+		NextScan:             "Volgende scan",
+		ZoomIn:               "Inzoomen",
+		ZoomOut:              "Uitzoomen",
+		ResetZoom:            "Zoom herstellen",
+		NoScans:              "Geen scans beschikbaar",
+		Citation:             "Dit bidprentje citeren",
+		CopyCitation:         "Kopiëren",
+		Copied:               "Gekopieerd",
+		Accessed:             "geraadpleegd",
+		Permalink:            "Permanente link",
+		Loading:              "Het archief wordt geladen",
+		LoadingHelp:          "Na een herstart kan dit enkele minuten duren. Deze pagina ververst vanzelf.",
+		LoadFailed:           "Het archief kon niet worden geladen",
+		LoadFailedHelp:       "Probeer het later opnieuw of neem contact op met de beheerder.",
 	},
 	"de": {
 		Search:               "Bidprentjes suchen",
@@ -197,6 +365,62 @@ var translations = map[string]Translations{
 		Of:                   "von",
 		ID:                   "ID",
 		ExactMatch:           "Nur exakte Übereinstimmungen",
+		Save:                 "Speichern",
+		SaveSuccess:          "Bidprentje erfolgreich gespeichert",
+		SaveError:            "Fehler beim Speichern des Bidprentje",
+		ScansHelp:            "Eine Scan-ID pro Zeile, ohne die .jpg Endung",
+		IDRequired:           "ID ist erforderlich",
+		InvalidDate:          "Daten müssen im JJJJ-MM-TT Format sein",
+		DeathBeforeBirth:     "Sterbedatum darf nicht vor dem Geburtsdatum liegen",
+		AlreadyExists:        "Ein Bidprentje mit dieser ID existiert bereits",
+		NotFound:             "Bidprentje nicht gefunden",
+		SelectScansFile:      "Scans-CSV-Datei auswählen (optional)",
+		RecordsSkipped:       "Datensätze übersprungen",
+		ImportErrors:         "Fehler",
+		RecentImports:        "Letzte Importe",
+		Status:               "Status",
+		Started:              "Gestartet",
+		AdvancedSearch:       "Erweiterte Suche",
+		BirthYear:            "Geburtsjahr",
+		DeathYear:            "Sterbejahr",
+		From:                 "Von",
+		To:                   "Bis",
+		Any:                  "Egal",
+		HasScans:             "Scans verfügbar",
+		RefineResults:        "Ergebnisse verfeinern",
+		BirthDecade:          "Geburtsjahrzehnt",
+		DeathDecade:          "Sterbejahrzehnt",
+		SortBy:               "Sortieren nach",
+		Relevance:            "Relevanz",
+		Ascending:            "aufsteigend",
+		Descending:           "absteigend",
+		MatchMode:            "Suchmodus",
+		FuzzyMatch:           "Ähnliche Schreibweise",
+		PhoneticMatch:        "Klingt wie",
+		MatchedOn:            "Gefunden in",
+		Score:                "Score",
+		Export:               "Exportieren",
+		ExportCSV:            "CSV (zum erneuten Import)",
+		ExportCSVScanLinks:   "CSV mit Scan-Links (nicht zum Import)",
+		A2AFormat:            "A2A-XML-Dateien mit einem oder mehreren Datensätzen werden ebenfalls akzeptiert; die Scans stammen dann aus den Dokumenten, sofern keine Scans-CSV angegeben ist.",
+		BackToSearch:         "Zurück zur Suche",
+		Front:                "Vorderseite",
+		Reverse:              "Rückseite",
+		PreviousScan:         "Vorheriger Scan",
+		NextScan:             "Nächster Scan",
+		ZoomIn:               "Vergrößern",
+		ZoomOut:              "Verkleinern",
+		ResetZoom:            "Zoom zurücksetzen",
+		NoScans:              "Keine Scans verfügbar",
+		Citation:             "Dieses Bidprentje zitieren",
+		CopyCitation:         "Kopieren",
+		Copied:               "Kopiert",
+		Accessed:             "abgerufen am",
+		Permalink:            "Permanenter Link",
+		Loading:              "Das Archiv wird geladen",
+		LoadingHelp:          "Nach einem Neustart kann dies einige Minuten dauern. Diese Seite wird automatisch aktualisiert.",
+		LoadFailed:           "Das Archiv konnte nicht geladen werden",
+		LoadFailedHelp:       "Bitte versuchen Sie es später erneut oder wenden Sie sich an den Administrator.",
 	},
 }
 